@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/service"
+)
+
+// Ретроспективное сканирование старых проектов по текущим ключевым словам:
+//
+//	go run ./cmd/backfill -from 01.09.2025 -to 30.09.2025
+//	go run ./cmd/backfill -from-id 150000 -to-id 150500 -notify=false
+func main() {
+	logger.Init()
+
+	envPath := filepath.Join(config.GetProjectRoot(), ".env")
+	if err := godotenv.Load(envPath); err != nil {
+		logger.Log.Warnf("Не удалось загрузить .env: %v (возможно, используются переменные окружения)", err)
+	}
+
+	var r service.BackfillRange
+	var from, to string
+	flag.IntVar(&r.FromID, "from-id", 0, "минимальный ID проекта")
+	flag.IntVar(&r.ToID, "to-id", 0, "максимальный ID проекта")
+	flag.StringVar(&from, "from", "", "дата создания проекта с (ДД.ММ.ГГГГ)")
+	flag.StringVar(&to, "to", "", "дата создания проекта по (ДД.ММ.ГГГГ, включительно)")
+	flag.BoolVar(&r.Notify, "notify", true, "отправлять новые совпадения в каналы доставки")
+	flag.Parse()
+
+	var err error
+	if r.From, err = parseDate(from); err != nil {
+		logger.Log.Fatalf("неверная дата -from: %v", err)
+	}
+	if r.To, err = parseDate(to); err != nil {
+		logger.Log.Fatalf("неверная дата -to: %v", err)
+	}
+	if r.FromID == 0 && r.ToID == 0 && r.From.IsZero() && r.To.IsZero() {
+		logger.Log.Fatal("укажите диапазон: -from-id/-to-id или -from/-to")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := service.Backfill(ctx, r)
+	if errors.Is(err, context.Canceled) {
+		logger.Log.Warn("ретроспективное сканирование прервано")
+	} else if err != nil {
+		logger.Log.Fatalf("ошибка ретроспективного сканирования: %v", err)
+	}
+	if report == nil {
+		return
+	}
+
+	logger.Log.Infof("Проверено проектов: %d, новых совпадений: %d, уже отправленных: %d",
+		report.Projects, len(report.Matches), len(report.AlreadyDelivered))
+	if report.Truncated {
+		logger.Log.Warn("⚠️ Диапазон просмотрен не полностью: достигнут предел BACKFILL_MAX_PAGES, " +
+			"сузьте диапазон или увеличьте предел")
+	}
+	for _, n := range report.Matches {
+		logger.Log.Infof("🆕 %s | %s | ключи: %v", n.Title, n.FileURL, n.Keywords)
+	}
+	for _, n := range report.AlreadyDelivered {
+		logger.Log.Infof("✔️ %s | %s | ключи: %v", n.Title, n.FileURL, n.Keywords)
+	}
+}
+
+// parseDate разбирает дату ДД.ММ.ГГГГ или ГГГГ-ММ-ДД; пустая строка - без ограничения
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("02.01.2006", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
@@ -10,6 +13,7 @@ import (
 	"github.com/notenoughtea/law_scraper/internal/config"
 	"github.com/notenoughtea/law_scraper/internal/handler"
 	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/service"
 )
 
 func main() {
@@ -64,13 +68,31 @@ func main() {
 	logger.Log.Info("  /set_keywords слово1,слово2 - установить новые слова")
 	logger.Log.Info("  /add_keyword слово - добавить слово")
 	logger.Log.Info("  /remove_keyword слово - удалить слово")
+	logger.Log.Info("  /search запрос - поиск по проверенным документам")
 	logger.Log.Info("")
 	logger.Log.Info("════════════════════════════════════════")
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// HTTP API поиска (если задан API_ADDR)
+	handler.StartAPIServer(ctx)
+
 	// Обрабатываем входящие обновления
-	for update := range updates {
-		// Обрабатываем каждое обновление в отдельной горутине
-		go botHandler.HandleUpdate(update)
+	for {
+		select {
+		case update := <-updates:
+			// Обрабатываем каждое обновление в отдельной горутине
+			go botHandler.HandleUpdate(update)
+		case <-ctx.Done():
+			logger.Log.Info("Получен сигнал завершения, останавливаем бота...")
+			bot.StopReceivingUpdates()
+			// Сканирование, запущенное через /scan, дорабатывает текущие файлы и сохраняет состояние
+			service.CancelScan()
+			service.WaitScan()
+			logger.Log.Info("Бот остановлен")
+			return
+		}
 	}
 }
 
@@ -1,7 +1,8 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -17,14 +18,20 @@ import (
 	"github.com/notenoughtea/law_scraper/internal/service"
 )
 
-func runScanAndNotify() {
+func runScanAndNotify(ctx context.Context) {
 	logger.Log.Info("Запуск сканирования (параллельный режим)...")
 
-	const rssURL = "https://regulation.gov.ru/api/public/Rss/"
-	
 	// Используем параллельную версию с отправкой уведомлений сразу
 	// Это оптимизировано для слабых серверов (768MB RAM, 1 CPU)
-	matchesCount, err := service.ScanRSSAndProjectsParallel(rssURL)
+	matchesCount, err := service.RunScan(ctx)
+	if errors.Is(err, service.ErrScanInProgress) {
+		logger.Log.Warn("Предыдущее сканирование еще выполняется, запуск по расписанию пропущен")
+		return
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		logger.Log.Warnf("⏹ Сканирование прервано (%v). Найдено совпадений до остановки: %d", err, matchesCount)
+		return
+	}
 	if err != nil {
 		logger.Log.Errorf("Ошибка сканирования RSS/проектов: %v", err)
 		return
@@ -75,13 +82,17 @@ func main() {
 		logger.Log.Warnf("Не удалось загрузить .env: %v (возможно, используются переменные окружения)", err)
 	}
 
+	// Контекст отменяется по SIGINT/SIGTERM и останавливает текущее сканирование
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	schedule := config.GetCronSchedule()
 	logger.Log.Infof("Настройка расписания: %s", schedule)
 
 	c := cron.New()
 	
 	// Добавляем задачу по расписанию
-	_, err := c.AddFunc(schedule, runScanAndNotify)
+	_, err := c.AddFunc(schedule, func() { runScanAndNotify(ctx) })
 	if err != nil {
 		logger.Log.Fatalf("Ошибка настройки расписания: %v", err)
 	}
@@ -93,20 +104,25 @@ func main() {
 	// Запуск Telegram бота в отдельной горутине
 	go startTelegramBot()
 
+	// HTTP API поиска (если задан API_ADDR)
+	handler.StartAPIServer(ctx)
+
 	// Опционально: запуск сразу при старте
 	if os.Getenv("RUN_ON_START") == "true" {
 		logger.Log.Info("RUN_ON_START=true, запуск задачи сразу...")
-		runScanAndNotify()
+		runScanAndNotify(ctx)
 	}
 
 	// Ожидание сигнала завершения
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	
-	sig := <-sigChan
-	fmt.Printf("\nПолучен сигнал %v, завершение работы...\n", sig)
-	
-	c.Stop()
+	<-ctx.Done()
+	logger.Log.Info("Получен сигнал завершения, останавливаем работу...")
+
+	// Останавливаем планировщик и текущее сканирование (в т.ч. запущенное через бота),
+	// затем ждем, пока воркеры доработают текущие файлы и сохранят состояние
+	cronDone := c.Stop()
+	service.CancelScan()
+	<-cronDone.Done()
+	service.WaitScan()
 	logger.Log.Info("Крон-планировщик остановлен")
 }
 
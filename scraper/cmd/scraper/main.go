@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/notenoughtea/law_scraper/internal/clients"
 	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
 	"github.com/notenoughtea/law_scraper/internal/logger"
 	"github.com/notenoughtea/law_scraper/internal/repository"
 	"github.com/notenoughtea/law_scraper/internal/service"
@@ -26,19 +32,41 @@ func main() {
 		logger.Log.Infof("Найден кэш страниц (legacy): %d", len(cached))
 	}
 
+	// SIGINT/SIGTERM останавливают сканирование с сохранением состояния
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if timeout := config.GetScanTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	// 2) Новый поток: RSS -> проекты -> вложения -> KEYWORDS
 	const rssURL = "https://regulation.gov.ru/api/public/Rss/"
-	matches, err := service.ScanRSSAndProjects(rssURL)
-	if err != nil {
+	matches, err := service.ScanRSSAndProjects(ctx, rssURL)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		logger.Log.Warnf("сканирование прервано: %v", err)
+	} else if err != nil {
 		logger.Log.Panicf("ошибка сканирования RSS/проектов: %v", err)
 	}
+	// Каждое совпадение уже выведено конвейером и записано в журнал
 	logger.Log.Infof("Найдено совпадений: %d", len(matches))
-	for _, m := range matches {
-		logger.Log.Infof("Проект: %s, файл: %s, ключи: %v", m.ProjectURL, m.FileURL, m.Keywords)
-	}
 
-	// Сохранение первых 5 старых страниц для совместимости
-	if _, err := clients.GetActsList(); err != nil {
+	// Сохранение первых 5 страниц списка проектов для совместимости; pages.json
+	// перезаписывается только полностью загруженным списком
+	list, err := clients.GetActsList(ctx, clients.ActsQuery{MaxPages: 5})
+	if err != nil {
 		logger.Log.Warnf("legacy загрузка страниц не выполнена: %v", err)
+		return
+	}
+	projects := list.Projects
+	if len(projects) == 0 {
+		return
+	}
+	page := dto.ListResponse{Result: projects, Count: len(projects), TotalCount: len(projects), Page: 1}
+	if err := repository.SavePages([]dto.ListResponse{page}); err != nil {
+		logger.Log.Warnf("не удалось сохранить страницы: %v", err)
+		return
 	}
+	logger.Log.Infof("Страницы сохранены: проектов %d", len(projects))
 }
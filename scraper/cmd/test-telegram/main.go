@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 
@@ -64,7 +65,7 @@ func main() {
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Println()
 
-	if err := service.SendNotificationsFromFile(); err != nil {
+	if err := service.SendNotificationsFromFile(context.Background()); err != nil {
 		fmt.Printf("❌ Ошибка: %v\n", err)
 		return
 	}
@@ -0,0 +1,193 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// ActsFilter - отбор проектов из публичного списка. Пустые поля не ограничивают выборку,
+// значения внутри одного поля объединяются через ИЛИ, разные поля - через И.
+// Формат фильтров API сайта не документирован, поэтому отбор выполняется на нашей стороне:
+// предел страниц (ACTS_MAX_PAGES) считается по всему списку, а не по подходящим проектам,
+// и при узком фильтре результат может оказаться неполным (см. ErrActsTruncated)
+type ActsFilter struct {
+	// Departments - органы-разработчики: ID или название (без учета регистра)
+	Departments []string
+	// Okveds - коды ОКВЭД: "49" подходит и к "49.1", и к "49.10"
+	Okveds []string
+	// Procedures - процедуры: ID или название
+	Procedures []string
+	// Stages - стадии проекта
+	Stages []string
+	// RegulatoryImpacts - степени регулирующего воздействия
+	RegulatoryImpacts []string
+	// FromID, ToID - диапазон числовых ID проекта
+	FromID, ToID int
+	// From, To - диапазон даты создания (To включительно)
+	From, To time.Time
+}
+
+// Match проверяет, подходит ли проект под фильтр
+func (f ActsFilter) Match(p dto.Project) bool {
+	if !matchAny(f.Departments, p.DevelopedDepartment.ID, p.DevelopedDepartment.Description) {
+		return false
+	}
+	if !matchAny(f.Procedures, p.Procedure.ID, p.Procedure.Description) {
+		return false
+	}
+	if !matchAny(f.Stages, p.Stage) || !matchAny(f.RegulatoryImpacts, p.RegulatoryImpact) {
+		return false
+	}
+	if len(f.Okveds) > 0 && !f.matchOkved(p.Okveds) {
+		return false
+	}
+
+	id, created := p.NumericID(), p.CreationDate.Time
+	if (f.FromID > 0 || f.ToID > 0) && id == 0 {
+		return false
+	}
+	if f.FromID > 0 && id < f.FromID || f.ToID > 0 && id > f.ToID {
+		return false
+	}
+	if (!f.From.IsZero() || !f.To.IsZero()) && created.IsZero() {
+		return false
+	}
+	if !f.From.IsZero() && created.Before(f.From) || !f.To.IsZero() && !created.Before(f.To.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// olderThanRange сообщает, что проект старше нижней границы фильтра по ID или дате
+func (f ActsFilter) olderThanRange(p dto.Project) bool {
+	if id := p.NumericID(); f.FromID > 0 && id > 0 && id < f.FromID {
+		return true
+	}
+	created := p.CreationDate.Time
+	return !f.From.IsZero() && !created.IsZero() && created.Before(f.From)
+}
+
+func (f ActsFilter) matchOkved(okveds []dto.Okved) bool {
+	for _, want := range f.Okveds {
+		want = strings.TrimSpace(want)
+		for _, o := range okveds {
+			code := o.Code()
+			if code == want || strings.HasPrefix(code, want+".") || strings.EqualFold(o.Description, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAny проверяет, что одно из значений совпадает с одним из вариантов; пустой список вариантов подходит всегда
+func matchAny(options []string, values ...string) bool {
+	if len(options) == 0 {
+		return true
+	}
+	for _, opt := range options {
+		opt = strings.TrimSpace(opt)
+		for _, v := range values {
+			if v != "" && strings.EqualFold(strings.TrimSpace(v), opt) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrActsTruncated передается последним элементом ListActs, если обход остановлен пределом страниц,
+// а список еще не закончился: подходящие проекты могли остаться на непросмотренных страницах
+var ErrActsTruncated = errors.New("список проектов просмотрен не полностью")
+
+// ActsQuery - параметры обхода списка проектов; нулевые значения берутся из настроек
+type ActsQuery struct {
+	Filter ActsFilter
+	// PageSize - размер страницы (ACTS_PAGE_SIZE)
+	PageSize int
+	// MaxPages - сколько страниц просмотреть не больше (ACTS_MAX_PAGES)
+	MaxPages int
+}
+
+// ListActs обходит публичный список проектов постранично и отдает подходящие под фильтр проекты.
+// Обход заканчивается, когда просмотрено TotalCount проектов, страница пуста, вся страница старше
+// нижней границы фильтра (список идет от новых проектов к старым) или достигнут MaxPages
+// (тогда последним элементом передается ErrActsTruncated). Ошибка загрузки страницы
+// тоже передается последним элементом
+func ListActs(ctx context.Context, q ActsQuery) iter.Seq2[dto.Project, error] {
+	pageSize, maxPages := q.PageSize, q.MaxPages
+	if pageSize <= 0 {
+		pageSize = config.GetActsPageSize()
+	}
+	if maxPages <= 0 {
+		maxPages = config.GetActsMaxPages()
+	}
+
+	return func(yield func(dto.Project, error) bool) {
+		for page := 1; ; page++ {
+			if page > maxPages {
+				logger.Log.Warnf("⚠️ Список проектов просмотрен не полностью: достигнут предел в %d страниц", maxPages)
+				yield(dto.Project{}, fmt.Errorf("%w: достигнут предел в %d страниц", ErrActsTruncated, maxPages))
+				return
+			}
+			if err := ctx.Err(); err != nil {
+				yield(dto.Project{}, err)
+				return
+			}
+			resp, err := FetchActsPage(ctx, page, pageSize)
+			if err != nil {
+				yield(dto.Project{}, fmt.Errorf("страница %d списка проектов: %w", page, err))
+				return
+			}
+			if len(resp.Result) == 0 {
+				return
+			}
+
+			older := 0
+			for _, p := range resp.Result {
+				if q.Filter.olderThanRange(p) {
+					older++
+					continue
+				}
+				if q.Filter.Match(p) && !yield(p, nil) {
+					return
+				}
+			}
+			if older == len(resp.Result) || page*pageSize >= resp.TotalCount {
+				return
+			}
+		}
+	}
+}
+
+// ActsList - проекты списка, подходящие под фильтр
+type ActsList struct {
+	Projects []dto.Project
+	// Truncated - обход остановлен пределом страниц до конца списка
+	Truncated bool
+}
+
+// GetActsList собирает все проекты списка, подходящие под фильтр. Предел страниц ошибкой
+// не считается, а отмечается в Truncated
+func GetActsList(ctx context.Context, q ActsQuery) (ActsList, error) {
+	var list ActsList
+	for p, err := range ListActs(ctx, q) {
+		if errors.Is(err, ErrActsTruncated) {
+			list.Truncated = true
+			break
+		}
+		if err != nil {
+			return list, err
+		}
+		list.Projects = append(list.Projects, p)
+	}
+	return list, nil
+}
@@ -0,0 +1,318 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+const (
+	smtpTimeout            = 60 * time.Second
+	defaultMaxAttachmentMB = 20
+)
+
+// EmailNotifier отправляет совпадения письмом через SMTP.
+// В режиме дайджеста совпадения копятся до вызова Flush
+type EmailNotifier struct {
+	name string
+	cfg  dto.EmailSinkConfig
+
+	mu      sync.Mutex
+	pending []dto.Notification
+}
+
+// emailAttachment - файл, прикладываемый к письму
+type emailAttachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// NewEmailNotifier создает канал доставки по email
+func NewEmailNotifier(name string, cfg dto.EmailSinkConfig) *EmailNotifier {
+	cfg.TLS = strings.ToLower(cfg.TLS)
+	if cfg.TLS == "" {
+		cfg.TLS = "starttls"
+	}
+	if cfg.Port == 0 {
+		if cfg.TLS == "tls" {
+			cfg.Port = 465
+		} else {
+			cfg.Port = 587
+		}
+	}
+	if cfg.MaxAttachmentMB <= 0 {
+		cfg.MaxAttachmentMB = defaultMaxAttachmentMB
+	}
+	return &EmailNotifier{name: name, cfg: cfg}
+}
+
+func (e *EmailNotifier) Name() string {
+	return e.name
+}
+
+// Notify отправляет письмо о совпадении, а в режиме дайджеста только запоминает его
+func (e *EmailNotifier) Notify(ctx context.Context, n dto.Notification) error {
+	if e.cfg.Digest {
+		e.mu.Lock()
+		e.pending = append(e.pending, n)
+		e.mu.Unlock()
+		logger.Log.Infof("📧 Совпадение %s добавлено в дайджест %s", n.FileURL, e.name)
+		return nil
+	}
+
+	subject := "Найдено совпадение"
+	if n.Title != "" {
+		subject += ": " + n.Title
+	}
+	return e.send(ctx, subject, []dto.Notification{n})
+}
+
+// Flush отправляет накопленный дайджест одним письмом
+func (e *EmailNotifier) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	pending := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	return e.send(ctx, fmt.Sprintf("Найдено совпадений: %d", len(pending)), pending)
+}
+
+// send собирает письмо из одного или нескольких совпадений и отправляет его
+func (e *EmailNotifier) send(ctx context.Context, subject string, matches []dto.Notification) error {
+	if len(e.cfg.To) == 0 {
+		return fmt.Errorf("не указаны получатели")
+	}
+	if e.cfg.SubjectPrefix != "" {
+		subject = e.cfg.SubjectPrefix + " " + subject
+	}
+
+	var attachments []emailAttachment
+	var notes []string
+	budget := int64(e.cfg.MaxAttachmentMB) << 20
+	if memory := config.GetAttachmentMemoryBudgetBytes(); memory < budget {
+		budget = memory
+	}
+	seen := map[string]bool{}
+
+	var body strings.Builder
+	body.WriteString("<html><body>\n")
+	for i, n := range matches {
+		if i > 0 {
+			body.WriteString("<hr>\n")
+		}
+		body.WriteString(emailHTML(n))
+
+		if e.cfg.NoAttachments || !n.IsFile() || seen[n.FileURL] {
+			continue
+		}
+		seen[n.FileURL] = true
+
+		att, err := downloadAttachment(ctx, n, budget)
+		if err != nil {
+			logger.Log.Warnf("Не удалось приложить файл %s к письму: %v", n.FileURL, err)
+			notes = append(notes, fmt.Sprintf("Файл %s не приложен: %v", n.FileURL, err))
+			continue
+		}
+		budget -= int64(len(att.Data))
+		attachments = append(attachments, att)
+	}
+	for _, note := range notes {
+		fmt.Fprintf(&body, "<p><i>%s</i></p>\n", EscapeHTML(note))
+	}
+	body.WriteString("</body></html>\n")
+
+	msg, err := buildEmailMessage(e.cfg.From, e.cfg.To, subject, body.String(), attachments)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(e.cfg.Host, fmt.Sprint(e.cfg.Port))
+	logger.Log.Infof("📧 Отправка письма через %s (%s) получателям %v, вложений: %d",
+		addr, e.cfg.TLS, e.cfg.To, len(attachments))
+	if err := e.sendMail(ctx, addr, msg); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("отправка письма прервана (%w): %v", ctx.Err(), err)
+		}
+		return fmt.Errorf("ошибка отправки письма: %w", err)
+	}
+	logger.Log.Infof("✅ Письмо отправлено: %s", subject)
+	return nil
+}
+
+// sendMail выполняет SMTP-диалог с учетом режима TLS и авторизации. Отмена ctx прерывает
+// диалог на любом шаге
+func (e *EmailNotifier) sendMail(ctx context.Context, addr string, msg []byte) error {
+	tlsConfig := &tls.Config{
+		ServerName:         e.cfg.Host,
+		InsecureSkipVerify: e.cfg.InsecureSkipVerify,
+	}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	if e.cfg.TLS == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	// При отмене ctx сдвигаем срок в прошлое: текущее чтение или запись сразу завершатся ошибкой
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.cfg.TLS == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("сервер %s не поддерживает STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("ошибка STARTTLS: %w", err)
+		}
+	}
+
+	if e.cfg.Username != "" {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+				return fmt.Errorf("ошибка авторизации: %w", err)
+			}
+		}
+	}
+
+	if err := c.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range e.cfg.To {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("получатель %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// emailHTML формирует HTML-фрагмент письма для одного совпадения по шаблону
+func emailHTML(n dto.Notification) string {
+	data := newTemplateData(n)
+	data.DescriptionMaxLen = 0
+	body, err := renderTemplate(TemplateEmail, data)
+	if err != nil {
+		logger.Log.Errorf("Ошибка шаблона %s: %v", TemplateEmail, err)
+		return "<pre>" + EscapeHTML(plainTextMessage(n)) + "</pre>\n"
+	}
+	return body + "\n"
+}
+
+// buildEmailMessage собирает MIME-письмо multipart/mixed с HTML-телом и вложениями
+func buildEmailMessage(from string, to []string, subject, htmlBody string, attachments []emailAttachment) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mw.Boundary())
+
+	htmlHeader := textproto.MIMEHeader{}
+	htmlHeader.Set("Content-Type", "text/html; charset=UTF-8")
+	htmlHeader.Set("Content-Transfer-Encoding", "base64")
+	part, err := mw.CreatePart(htmlHeader)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(part, []byte(htmlBody)); err != nil {
+		return nil, err
+	}
+
+	for _, att := range attachments {
+		h := textproto.MIMEHeader{}
+		mediaType, params, err := mime.ParseMediaType(att.ContentType)
+		if err != nil {
+			mediaType, params = "application/octet-stream", map[string]string{}
+		}
+		params["name"] = att.Name
+		h.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Name}))
+		h.Set("Content-Transfer-Encoding", "base64")
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, att.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 пишет данные в base64 строками по 76 символов (RFC 2045)
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+// downloadAttachment берет файл для вложения из кэша или скачивает его, не превышая лимит размера
+func downloadAttachment(ctx context.Context, n dto.Notification, limit int64) (emailAttachment, error) {
+	if limit <= 0 {
+		return emailAttachment{}, fmt.Errorf("превышен лимит размера вложений")
+	}
+
+	data, name, contentType, err := fetchAttachment(ctx, n, limit)
+	if err != nil {
+		return emailAttachment{}, err
+	}
+	return emailAttachment{Name: name, ContentType: contentType, Data: data}, nil
+}
@@ -0,0 +1,293 @@
+package clients
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/dto"
+)
+
+// smtpMessage - письмо, принятое тестовым SMTP-сервером
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpStandIn - минимальный SMTP-сервер на 127.0.0.1 без TLS и авторизации
+type smtpStandIn struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpStandIn{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *smtpStandIn) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var msg smtpMessage
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg = smtpMessage{from: smtpPath(line)}
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = append(msg.to, smtpPath(line))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				// Снимаем экранирование точки в начале строки (RFC 5321, 4.5.2)
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// smtpPath возвращает адрес из угловых скобок команды MAIL FROM или RCPT TO
+func smtpPath(line string) string {
+	start := strings.IndexByte(line, '<')
+	end := strings.IndexByte(line, '>')
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+// mimePart - разобранная часть письма с декодированным содержимым
+type mimePart struct {
+	header mail.Header
+	body   string
+}
+
+// parseEmail разбирает письмо multipart/mixed и декодирует base64-части
+func parseEmail(t *testing.T, data string) (*mail.Message, []mimePart) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("письмо не разобрано: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, ожидался multipart/mixed", msg.Header.Get("Content-Type"))
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []mimePart
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("часть письма не разобрана: %v", err)
+		}
+		if enc := p.Header.Get("Content-Transfer-Encoding"); enc != "base64" {
+			t.Fatalf("Content-Transfer-Encoding = %q, ожидался base64", enc)
+		}
+		body, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+		if err != nil {
+			t.Fatalf("base64 не декодирован: %v", err)
+		}
+		parts = append(parts, mimePart{header: mail.Header(p.Header), body: string(body)})
+	}
+	return msg, parts
+}
+
+// startFileServer отдает файл совпадения с заданным содержимым
+func startFileServer(t *testing.T, content string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="project.txt"`)
+		io.WriteString(w, content)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestEmailNotifier(port int, digest bool) *EmailNotifier {
+	return NewEmailNotifier("test-email", dto.EmailSinkConfig{
+		Host:   "127.0.0.1",
+		Port:   port,
+		TLS:    "none",
+		From:   "scraper@example.org",
+		To:     []string{"a@example.org", "b@example.org"},
+		Digest: digest,
+	})
+}
+
+func TestEmailNotifierSendsMatchWithAttachment(t *testing.T) {
+	t.Setenv("ATTACHMENT_CACHE_MAX_MB", "0")
+	smtpSrv := startSMTPStandIn(t)
+	const content = "текст проекта с ключевым словом"
+	fileSrv := startFileServer(t, content)
+
+	n := newTestEmailNotifier(smtpSrv.port(), false)
+	match := dto.Notification{
+		ProjectURL: "https://regulation.gov.ru/projects/1",
+		FileURL:    fileSrv.URL + "/GetFile/1",
+		Title:      "Проект постановления",
+		Keywords:   []string{"ключевым"},
+	}
+	if err := n.Notify(context.Background(), match); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	messages := smtpSrv.received()
+	if len(messages) != 1 {
+		t.Fatalf("писем %d, ожидалось 1", len(messages))
+	}
+	got := messages[0]
+	if got.from != "scraper@example.org" || strings.Join(got.to, ",") != "a@example.org,b@example.org" {
+		t.Errorf("конверт: from %q, to %v", got.from, got.to)
+	}
+
+	msg, parts := parseEmail(t, got.data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Найдено совпадение: Проект постановления" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("частей письма %d, ожидалось 2 (HTML и вложение)", len(parts))
+	}
+	if ct := parts[0].header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("первая часть: Content-Type %q", ct)
+	}
+	if !strings.Contains(parts[0].body, "Проект постановления") {
+		t.Errorf("в HTML нет заголовка проекта: %s", parts[0].body)
+	}
+
+	disposition, params, err := mime.ParseMediaType(parts[1].header.Get("Content-Disposition"))
+	if err != nil || disposition != "attachment" || params["filename"] != "project.txt" {
+		t.Errorf("вложение: Content-Disposition %q", parts[1].header.Get("Content-Disposition"))
+	}
+	if parts[1].body != content {
+		t.Errorf("содержимое вложения %q, ожидалось %q", parts[1].body, content)
+	}
+}
+
+func TestEmailNotifierDigestFlush(t *testing.T) {
+	t.Setenv("ATTACHMENT_CACHE_MAX_MB", "0")
+	smtpSrv := startSMTPStandIn(t)
+	fileSrv := startFileServer(t, "общий файл")
+
+	n := newTestEmailNotifier(smtpSrv.port(), true)
+	if _, ok := Notifier(n).(Flusher); !ok {
+		t.Fatal("EmailNotifier должен реализовывать Flusher")
+	}
+	matches := []dto.Notification{
+		{ProjectURL: "https://regulation.gov.ru/projects/1", FileURL: fileSrv.URL + "/GetFile/2", Title: "Первый проект"},
+		{ProjectURL: "https://regulation.gov.ru/projects/1", FileURL: fileSrv.URL + "/GetFile/2", Title: "Второй проект"},
+	}
+	for _, m := range matches {
+		if err := n.Notify(context.Background(), m); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+	if got := len(smtpSrv.received()); got != 0 {
+		t.Fatalf("до Flush отправлено писем: %d", got)
+	}
+
+	if err := FlushNotifier(context.Background(), n); err != nil {
+		t.Fatalf("FlushNotifier: %v", err)
+	}
+	messages := smtpSrv.received()
+	if len(messages) != 1 {
+		t.Fatalf("писем %d, ожидался один дайджест", len(messages))
+	}
+	msg, parts := parseEmail(t, messages[0].data)
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Найдено совпадений: "+strconv.Itoa(len(matches)) {
+		t.Errorf("Subject = %q", subject)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("частей письма %d, ожидалось 2: одинаковый файл прикладывается один раз", len(parts))
+	}
+	for _, m := range matches {
+		if !strings.Contains(parts[0].body, m.Title) {
+			t.Errorf("в дайджесте нет совпадения %q", m.Title)
+		}
+	}
+
+	// Повторный Flush без новых совпадений ничего не отправляет
+	if err := FlushNotifier(context.Background(), n); err != nil {
+		t.Fatalf("повторный FlushNotifier: %v", err)
+	}
+	if got := len(smtpSrv.received()); got != 1 {
+		t.Errorf("после пустого Flush писем %d, ожидалось 1", got)
+	}
+}
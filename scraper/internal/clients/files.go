@@ -0,0 +1,382 @@
+package clients
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+)
+
+// FileType - расширение и MIME-тип документа
+type FileType struct {
+	Ext         string
+	ContentType string
+}
+
+var (
+	typePDF  = FileType{".pdf", "application/pdf"}
+	typeDOCX = FileType{".docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"}
+	typeXLSX = FileType{".xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}
+	typePPTX = FileType{".pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation"}
+	typeODT  = FileType{".odt", "application/vnd.oasis.opendocument.text"}
+	typeZIP  = FileType{".zip", "application/zip"}
+	typeDOC  = FileType{".doc", "application/msword"}
+	typeRTF  = FileType{".rtf", "application/rtf"}
+	typeTXT  = FileType{".txt", "text/plain; charset=utf-8"}
+	typeBIN  = FileType{".bin", "application/octet-stream"}
+)
+
+// typesByExt - известные расширения документов
+var typesByExt = map[string]FileType{
+	".pdf": typePDF, ".docx": typeDOCX, ".xlsx": typeXLSX, ".pptx": typePPTX, ".odt": typeODT,
+	".zip": typeZIP, ".doc": typeDOC, ".rtf": typeRTF, ".txt": typeTXT,
+	".xls": {".xls", "application/vnd.ms-excel"},
+}
+
+// definiteTypes - типы с однозначной сигнатурой; у остальных (OLE, ZIP, текст)
+// одна сигнатура у нескольких форматов, поэтому расширению из имени доверяем больше
+var definiteTypes = map[FileType]bool{
+	typePDF: true, typeDOCX: true, typeXLSX: true, typePPTX: true, typeODT: true, typeRTF: true,
+}
+
+// SniffFileType определяет тип документа по сигнатуре (magic bytes).
+// Для ZIP-контейнеров (docx/xlsx/pptx/odt) нужны полные данные файла, иначе вернется .zip
+func SniffFileType(data []byte) FileType {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return typePDF
+	case bytes.HasPrefix(data, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}):
+		return typeDOC
+	case bytes.HasPrefix(data, []byte(`{\rtf`)):
+		return typeRTF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return sniffZipType(data)
+	}
+
+	// DetectContentType смотрит только на первые 512 байт
+	if len(data) > 0 && strings.HasPrefix(http.DetectContentType(data), "text/plain") {
+		return typeTXT
+	}
+	return typeBIN
+}
+
+// sniffZipType различает офисные форматы внутри ZIP по именам файлов
+func sniffZipType(data []byte) FileType {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		// Не полный файл: пробуем найти характерные имена в локальных заголовках
+		switch {
+		case bytes.Contains(data, []byte("word/")):
+			return typeDOCX
+		case bytes.Contains(data, []byte("xl/")):
+			return typeXLSX
+		case bytes.Contains(data, []byte("ppt/")):
+			return typePPTX
+		}
+		return typeZIP
+	}
+	for _, f := range zr.File {
+		switch {
+		case strings.HasPrefix(f.Name, "word/"):
+			return typeDOCX
+		case strings.HasPrefix(f.Name, "xl/"):
+			return typeXLSX
+		case strings.HasPrefix(f.Name, "ppt/"):
+			return typePPTX
+		case f.Name == "mimetype":
+			if rc, err := f.Open(); err == nil {
+				b, _ := io.ReadAll(io.LimitReader(rc, 100))
+				rc.Close()
+				if strings.Contains(string(b), "opendocument.text") {
+					return typeODT
+				}
+			}
+		}
+	}
+	return typeZIP
+}
+
+// FileNameFromDisposition извлекает имя файла из заголовка Content-Disposition
+// (поддерживаются filename* по RFC 5987 и имена в процентной кодировке)
+func FileNameFromDisposition(disposition string) string {
+	if disposition == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(disposition)
+	if err != nil {
+		return ""
+	}
+	name := params["filename"]
+	if name == "" {
+		return ""
+	}
+	if strings.Contains(name, "%") {
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+	}
+	return sanitizeFileName(name)
+}
+
+// sanitizeFileName убирает путь и символы, недопустимые в именах файлов
+func sanitizeFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Base(strings.TrimSpace(name))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '"', '<', '>', ':', '|', '?', '*':
+			return '_'
+		}
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+// DocumentFileName строит имя файла для отправки: имя из Content-Disposition или ID файла,
+// расширение по сигнатуре, если его нет, и префикс с ID проекта
+func DocumentFileName(projectID, fileURL, disposition string, data []byte) (string, string) {
+	return documentFileName(projectID, fileURL, FileNameFromDisposition(disposition), data)
+}
+
+// StageFileName - как DocumentFileName, но без Content-Disposition берет исходное имя файла из стадии проекта
+func StageFileName(projectID string, f dto.StageFile, disposition string, data []byte) (string, string) {
+	name := FileNameFromDisposition(disposition)
+	if name == "" {
+		name = sanitizeFileName(f.Name)
+	}
+	return documentFileName(projectID, f.URL, name, data)
+}
+
+func documentFileName(projectID, fileURL, name string, data []byte) (string, string) {
+	sniffed := SniffFileType(data)
+
+	if name == "" {
+		name = path.Base(fileURL)
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	ft, known := typesByExt[ext]
+	if !known {
+		ft = sniffed
+		name += sniffed.Ext
+	} else if definiteTypes[sniffed] && sniffed.Ext != ext {
+		// Расширение в имени не соответствует содержимому - доверяем содержимому
+		logger.Log.Warnf("Расширение %s файла %s не совпадает с содержимым (%s)", ext, name, sniffed.Ext)
+		name = strings.TrimSuffix(name, path.Ext(name)) + sniffed.Ext
+		ft = sniffed
+	}
+
+	if projectID != "" && !strings.HasPrefix(name, projectID+"_") {
+		name = projectID + "_" + name
+	}
+	return name, ft.ContentType
+}
+
+// FileIDFromURL возвращает ID файла из ссылки вида .../GetFile/<id>
+func FileIDFromURL(fileURL string) string {
+	if u, err := url.Parse(fileURL); err == nil {
+		fileURL = u.Path
+	}
+	id := path.Base(fileURL)
+	if id == "." || id == "/" {
+		return ""
+	}
+	return id
+}
+
+// CacheAttachment сохраняет уже скачанный файл в кэш вложений, чтобы при отправке
+// документом не скачивать его второй раз
+func CacheAttachment(fileURL string, r io.Reader, fileName, contentType string) {
+	if _, err := repository.StoreAttachment(FileIDFromURL(fileURL), r, fileName, contentType); err != nil {
+		logger.Log.Warnf("Не удалось сохранить %s в кэш вложений: %v", fileURL, err)
+	}
+}
+
+// ErrAttachmentTooLarge - файл больше допустимого размера для отправки
+var ErrAttachmentTooLarge = errors.New("файл слишком большой")
+
+// attachmentHeadSize - сколько первых байт файла смотрим для определения типа
+const attachmentHeadSize = 4096
+
+// attachment - открытый для потокового чтения файл совпадения
+type attachment struct {
+	io.ReadCloser
+	Name        string
+	ContentType string
+	// Size - размер в байтах или -1, если он заранее неизвестен
+	Size int64
+}
+
+// openAttachment открывает файл совпадения для потокового чтения: из кэша вложений,
+// а если его там нет - скачивает в кэш (или читает прямо из ответа, если кэш отключен).
+// Если размер файла больше limit, возвращается ErrAttachmentTooLarge; размер проверяется
+// сначала по Content-Length, затем по фактически прочитанным байтам
+func openAttachment(ctx context.Context, n dto.Notification, limit int64) (*attachment, error) {
+	fileID := FileIDFromURL(n.FileURL)
+	if a, err := openCachedAttachment(n, fileID, limit); err != nil || a != nil {
+		return a, err
+	}
+
+	logger.Log.Infof("Скачивание файла с %s...", n.FileURL)
+	resp, err := GetRegulation(ctx, n.FileURL, "*/*")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка скачивания файла: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("ошибка при скачивании файла: статус %d", resp.StatusCode)
+	}
+	if limit > 0 && resp.ContentLength > limit {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d байт", ErrAttachmentTooLarge, resp.ContentLength)
+	}
+
+	// Тип и имя определяем по первым байтам, не читая файл целиком
+	br := bufio.NewReaderSize(resp.Body, attachmentHeadSize)
+	head, _ := br.Peek(attachmentHeadSize)
+	name, contentType := DocumentFileName(n.ProjectID, n.FileURL, resp.Header.Get("Content-Disposition"), head)
+
+	var body io.Reader = br
+	if limit > 0 {
+		body = &limitedReader{r: br, left: limit}
+	}
+
+	// Файл заведомо больше кэша не читаем в него, а сразу отдаем из ответа
+	var entry *repository.CachedAttachment
+	var spill io.ReadCloser
+	if cacheMax := config.GetAttachmentCacheMaxBytes(); cacheMax > 0 && resp.ContentLength <= cacheMax {
+		entry, spill, err = repository.StoreAttachmentSpill(fileID, body, name, contentType)
+	}
+	if err != nil {
+		resp.Body.Close()
+		if errors.Is(err, ErrAttachmentTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("ошибка чтения файла: %w", err)
+	}
+	if entry != nil {
+		resp.Body.Close()
+		a, err := openCachedAttachment(n, fileID, limit)
+		if err == nil && a == nil {
+			err = fmt.Errorf("файл %s пропал из кэша вложений", n.FileURL)
+		}
+		return a, err
+	}
+
+	// Кэш отключен или файл в него не поместился - читаем прямо из ответа,
+	// начиная с уже прочитанной в кэш части
+	if n.FileName != "" {
+		name = n.FileName
+	}
+	var closer io.Closer = resp.Body
+	if spill != nil {
+		body = io.MultiReader(spill, body)
+		closer = multiCloser{spill, resp.Body}
+	}
+	return &attachment{
+		ReadCloser:  readCloser{Reader: body, Closer: closer},
+		Name:        name,
+		ContentType: contentType,
+		Size:        resp.ContentLength,
+	}, nil
+}
+
+// openCachedAttachment открывает файл из кэша вложений. Возвращает nil без ошибки,
+// если файла в кэше нет
+func openCachedAttachment(n dto.Notification, fileID string, limit int64) (*attachment, error) {
+	entry, f, ok := repository.OpenAttachment(fileID)
+	if !ok {
+		return nil, nil
+	}
+	if limit > 0 && entry.Size > limit {
+		f.Close()
+		return nil, fmt.Errorf("%w: %d байт", ErrAttachmentTooLarge, entry.Size)
+	}
+	logger.Log.Infof("📦 Файл %s взят из кэша вложений (%d байт)", n.FileURL, entry.Size)
+
+	name, contentType := entry.FileName, entry.ContentType
+	if name == "" || contentType == "" {
+		head := make([]byte, attachmentHeadSize)
+		k, _ := io.ReadFull(f, head)
+		name, contentType = DocumentFileName(n.ProjectID, n.FileURL, "", head[:k])
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if n.FileName != "" {
+		name = n.FileName
+	}
+	return &attachment{ReadCloser: f, Name: name, ContentType: contentType, Size: entry.Size}, nil
+}
+
+// fetchAttachment читает файл совпадения целиком в память (не больше limit байт)
+func fetchAttachment(ctx context.Context, n dto.Notification, limit int64) (data []byte, name, contentType string, err error) {
+	a, err := openAttachment(ctx, n, limit)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer a.Close()
+
+	data, err = io.ReadAll(a)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return data, a.Name, a.ContentType, nil
+}
+
+// limitedReader возвращает ErrAttachmentTooLarge, если данных больше left байт
+type limitedReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.left < 0 {
+		return 0, ErrAttachmentTooLarge
+	}
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		return n, ErrAttachmentTooLarge
+	}
+	return n, err
+}
+
+// readCloser объединяет Reader с Closer исходного потока
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// multiCloser закрывает все перечисленные объекты
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var errs []error
+	for _, c := range m {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,135 @@
+package clients
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// hostLimiter ограничивает частоту и число одновременных запросов к одному хосту
+type hostLimiter struct {
+	slots    chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+
+	stats HostStats
+}
+
+// HostStats - статистика ожидания лимитера по хосту
+type HostStats struct {
+	Host      string
+	Requests  int
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+// AvgWait возвращает среднее время ожидания запроса
+func (s HostStats) AvgWait() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Requests)
+}
+
+var (
+	hostLimitersMutex sync.Mutex
+	hostLimiters      = map[string]*hostLimiter{}
+)
+
+// limiterFor возвращает лимитер хоста, создавая его с настройками HOST_RPS и HOST_MAX_INFLIGHT
+func limiterFor(host string) *hostLimiter {
+	hostLimitersMutex.Lock()
+	defer hostLimitersMutex.Unlock()
+
+	l, ok := hostLimiters[host]
+	if !ok {
+		l = &hostLimiter{
+			slots: make(chan struct{}, config.GetHostMaxInFlight()),
+			stats: HostStats{Host: host},
+		}
+		if rps := config.GetHostRateLimit(); rps > 0 {
+			l.interval = time.Duration(float64(time.Second) / rps)
+		}
+		hostLimiters[host] = l
+	}
+	return l
+}
+
+// acquire ждет свободного слота и своей очереди по частоте запросов.
+// Возвращает функцию, освобождающую слот; ее нужно вызвать по завершении запроса
+func (l *hostLimiter) acquire(ctx context.Context) (func(), error) {
+	start := time.Now()
+
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-l.slots }
+
+	if l.interval > 0 {
+		l.mu.Lock()
+		at := time.Now()
+		if l.next.After(at) {
+			at = l.next
+		}
+		l.next = at.Add(l.interval)
+		l.mu.Unlock()
+
+		if wait := time.Until(at); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				release()
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	waited := time.Since(start)
+	l.mu.Lock()
+	l.stats.Requests++
+	l.stats.TotalWait += waited
+	if waited > l.stats.MaxWait {
+		l.stats.MaxWait = waited
+	}
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+// TakeHostStats возвращает статистику ожидания по хостам с момента прошлого вызова и сбрасывает ее
+func TakeHostStats() []HostStats {
+	hostLimitersMutex.Lock()
+	defer hostLimitersMutex.Unlock()
+
+	var out []HostStats
+	for host, l := range hostLimiters {
+		l.mu.Lock()
+		if l.stats.Requests > 0 {
+			out = append(out, l.stats)
+		}
+		l.stats = HostStats{Host: host}
+		l.mu.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
+
+// LogHostStats пишет в лог статистику ожидания лимитера и сбрасывает ее
+func LogHostStats() {
+	for _, s := range TakeHostStats() {
+		logger.Log.Infof("🚦 %s: запросов %d, ожидание в очереди: среднее %s, максимальное %s, всего %s",
+			s.Host, s.Requests, s.AvgWait().Round(time.Millisecond), s.MaxWait.Round(time.Millisecond),
+			s.TotalWait.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,232 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// maxRetryAfter - верхняя граница ожидания по заголовку Retry-After
+const maxRetryAfter = 5 * time.Minute
+
+var (
+	// errAttemptTimeout - причина отмены попытки, не получившей ответ за REQUEST_TIMEOUT_SECONDS
+	errAttemptTimeout = errors.New("таймаут попытки")
+	// errDownloadTimeout - причина отмены чтения тела ответа дольше DOWNLOAD_TIMEOUT_SECONDS
+	errDownloadTimeout = errors.New("таймаут загрузки")
+)
+
+var (
+	regulationClientOnce sync.Once
+	regulationClient     *http.Client
+	regulationSettings   config.HTTPSettings
+)
+
+// getRegulationClient возвращает общий HTTP-клиент для regulation.gov.ru.
+// Создается при первом обращении, когда .env уже загружен
+func getRegulationClient() (*http.Client, config.HTTPSettings) {
+	regulationClientOnce.Do(func() {
+		regulationSettings = config.GetHTTPSettings()
+
+		proxy := http.ProxyFromEnvironment
+		if regulationSettings.Proxy != "" {
+			if u, err := url.Parse(regulationSettings.Proxy); err == nil {
+				proxy = http.ProxyURL(u)
+			} else {
+				logger.Log.Warnf("Некорректный REGULATION_PROXY %q: %v", regulationSettings.Proxy, err)
+			}
+		}
+
+		regulationClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy: proxy,
+				DialContext: (&net.Dialer{
+					Timeout:   regulationSettings.ConnectTimeout,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSHandshakeTimeout:   regulationSettings.ConnectTimeout,
+				ResponseHeaderTimeout: regulationSettings.ReadTimeout,
+				MaxIdleConns:          20,
+				MaxIdleConnsPerHost:   10,
+				IdleConnTimeout:       90 * time.Second,
+				ForceAttemptHTTP2:     true,
+			},
+		}
+	})
+	return regulationClient, regulationSettings
+}
+
+// GetRegulation выполняет GET-запрос к regulation.gov.ru через общий клиент с повторами
+func GetRegulation(ctx context.Context, rawURL, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return DoRegulation(req)
+}
+
+// DoRegulation выполняет запрос через общий клиент regulation.gov.ru.
+// Получение ответа в каждой попытке ограничено REQUEST_TIMEOUT_SECONDS, чтение тела после
+// заголовков - DOWNLOAD_TIMEOUT_SECONDS, чтобы большие вложения успевали загрузиться.
+// При сетевых ошибках, таймаутах, 429 и 5xx запрос повторяется с экспоненциальной задержкой
+// со случайным разбросом, а заданная сервером задержка Retry-After соблюдается.
+// Перед каждой попыткой запрос ждет очереди лимитера своего хоста (HOST_RPS, HOST_MAX_INFLIGHT).
+// Ответ с другим статусом возвращается как есть - его проверяет вызывающий код
+func DoRegulation(req *http.Request) (*http.Response, error) {
+	client, settings := getRegulationClient()
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", settings.UserAgent)
+	}
+	ctx := req.Context()
+
+	limiter := limiterFor(req.URL.Host)
+
+	for attempt := 0; ; attempt++ {
+		// Ждем очереди лимитера хоста: слот занят, пока не закрыто тело ответа
+		release, err := limiter.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		attemptCtx, cancelAttempt := context.WithCancelCause(ctx)
+		timer := time.AfterFunc(config.GetRequestTimeout(), func() { cancelAttempt(errAttemptTimeout) })
+		cancel := func() {
+			timer.Stop()
+			cancelAttempt(nil)
+			release()
+		}
+		r := req.Clone(attemptCtx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		resp, err := client.Do(r)
+		if err != nil && context.Cause(attemptCtx) == errAttemptTimeout && ctx.Err() == nil {
+			err = fmt.Errorf("%w за %s: %w", errAttemptTimeout, config.GetRequestTimeout(), context.DeadlineExceeded)
+		}
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			// Заголовки получены: дальше чтение тела ограничено таймаутом загрузки.
+			// Слот лимитера занят до закрытия тела ответа
+			if timer.Stop() {
+				timer = time.AfterFunc(config.GetDownloadTimeout(), func() { cancelAttempt(errDownloadTimeout) })
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		if ctx.Err() != nil || (err != nil && !retryableError(err)) || attempt >= settings.MaxRetries {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			cancel()
+			return nil, fmt.Errorf("%s %s: статус %s после %d попыток", req.Method, req.URL, resp.Status, attempt+1)
+		}
+
+		delay := backoffDelay(settings.RetryBaseDelay, attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if ra, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = ra
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		cancel()
+
+		logger.Log.Warnf("🔁 %s %s: %s, повтор %d/%d через %s", req.Method, req.URL, reason, attempt+1, settings.MaxRetries, delay.Round(time.Millisecond))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryableStatus - статусы, при которых запрос имеет смысл повторить
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retryableError - сетевые ошибки и таймауты попытки (но не отмена всего запроса)
+func retryableError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// backoffDelay - экспоненциальная задержка с разбросом: от половины до полной base*2^attempt
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	d := base << attempt
+	if d <= 0 || d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или в виде HTTP-даты
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if sec, err := strconv.Atoi(v); err == nil && sec >= 0 {
+		d = time.Duration(sec) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+	} else {
+		return 0, false
+	}
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	return d, true
+}
+
+// cancelOnClose освобождает контекст попытки и слот лимитера при закрытии тела ответа
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,59 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// MatrixNotifier отправляет совпадения в комнату Matrix через Client-Server API
+type MatrixNotifier struct {
+	name  string
+	cfg   dto.MatrixSinkConfig
+	txnID atomic.Int64
+}
+
+// NewMatrixNotifier создает канал доставки в Matrix
+func NewMatrixNotifier(name string, cfg dto.MatrixSinkConfig) *MatrixNotifier {
+	m := &MatrixNotifier{name: name, cfg: cfg}
+	m.txnID.Store(time.Now().UnixNano())
+	return m
+}
+
+func (m *MatrixNotifier) Name() string {
+	return m.name
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+// Notify отправляет событие m.room.message с текстовой и HTML версией
+func (m *MatrixNotifier) Notify(ctx context.Context, n dto.Notification) error {
+	txn := m.txnID.Add(1)
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%d",
+		strings.TrimRight(m.cfg.HomeserverURL, "/"), url.PathEscape(m.cfg.RoomID), txn)
+
+	text := plainTextMessage(n)
+	msg := matrixMessage{
+		MsgType:       "m.text",
+		Body:          text,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: emailHTML(n),
+	}
+
+	logger.Log.Infof("🟩 Отправка совпадения %s в комнату Matrix %s", n.FileURL, m.cfg.RoomID)
+	return sendJSON(ctx, http.MethodPut, endpoint, map[string]string{
+		"Authorization": "Bearer " + m.cfg.AccessToken,
+	}, msg)
+}
@@ -0,0 +1,251 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// Notifier - канал доставки уведомлений о найденных совпадениях
+type Notifier interface {
+	// Name возвращает имя канала для логов
+	Name() string
+	// Notify доставляет одно совпадение
+	Notify(ctx context.Context, n dto.Notification) error
+}
+
+// Flusher реализуют каналы, которые копят совпадения (например, email-дайджест)
+// и отправляют их одним сообщением в конце сканирования
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// FlushNotifier отправляет накопленные совпадения, если канал это поддерживает
+func FlushNotifier(ctx context.Context, n Notifier) error {
+	if f, ok := n.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// SinkNotifier реализуют составные каналы, которые сообщают о доставке в каждый канал отдельно,
+// чтобы при повторной отправке не дублировать уведомление в уже доставленные каналы
+type SinkNotifier interface {
+	// NotifySinks отправляет совпадение во все каналы, кроме skip, и возвращает имена каналов,
+	// в которые оно доставлено
+	NotifySinks(ctx context.Context, n dto.Notification, skip map[string]bool) ([]string, error)
+}
+
+// NotifySinks отправляет совпадение во все каналы n, кроме перечисленных в skip;
+// обычный канал считается одним каналом с именем Name()
+func NotifySinks(ctx context.Context, n Notifier, match dto.Notification, skip map[string]bool) ([]string, error) {
+	if s, ok := n.(SinkNotifier); ok {
+		return s.NotifySinks(ctx, match, skip)
+	}
+	if skip[n.Name()] {
+		return nil, nil
+	}
+	if err := n.Notify(ctx, match); err != nil {
+		return nil, err
+	}
+	return []string{n.Name()}, nil
+}
+
+// NewNotifier создает канал доставки по его описанию из data/notifiers.json
+func NewNotifier(cfg dto.SinkConfig) (Notifier, error) {
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Type
+	}
+
+	var n Notifier
+	switch strings.ToLower(cfg.Type) {
+	case "telegram":
+		n = NewTelegramNotifier(name, cfg.Telegram)
+	case "email", "smtp":
+		if cfg.Email == nil {
+			return nil, fmt.Errorf("канал %s: не заданы настройки email", name)
+		}
+		n = NewEmailNotifier(name, *cfg.Email)
+	case "webhook":
+		if cfg.Webhook == nil || cfg.Webhook.URL == "" {
+			return nil, fmt.Errorf("канал %s: не задан url вебхука", name)
+		}
+		n = NewWebhookNotifier(name, *cfg.Webhook)
+	case "slack", "mattermost":
+		if cfg.Slack == nil || cfg.Slack.WebhookURL == "" {
+			return nil, fmt.Errorf("канал %s: не задан webhookUrl", name)
+		}
+		n = NewSlackNotifier(name, *cfg.Slack)
+	case "matrix":
+		if cfg.Matrix == nil || cfg.Matrix.HomeserverURL == "" || cfg.Matrix.RoomID == "" {
+			return nil, fmt.Errorf("канал %s: не заданы homeserverUrl или roomId", name)
+		}
+		n = NewMatrixNotifier(name, *cfg.Matrix)
+	default:
+		return nil, fmt.Errorf("канал %s: неизвестный тип %q", name, cfg.Type)
+	}
+
+	return &filteredNotifier{Notifier: n, filter: cfg.Filter}, nil
+}
+
+// filteredNotifier пропускает в канал только совпадения, подходящие под фильтр
+type filteredNotifier struct {
+	Notifier
+	filter dto.SinkFilter
+}
+
+func (f *filteredNotifier) Notify(ctx context.Context, n dto.Notification) error {
+	if !MatchesFilter(f.filter, n) {
+		logger.Log.Debugf("Канал %s: совпадение %s не проходит фильтр", f.Name(), n.FileURL)
+		return nil
+	}
+	return f.Notifier.Notify(ctx, n)
+}
+
+func (f *filteredNotifier) Flush(ctx context.Context) error {
+	return FlushNotifier(ctx, f.Notifier)
+}
+
+// MatchesFilter проверяет, подходит ли совпадение под фильтр канала
+func MatchesFilter(filter dto.SinkFilter, n dto.Notification) bool {
+	if filter.FilesOnly && !n.IsFile() {
+		return false
+	}
+	if !matchesMetadata(filter, n) {
+		return false
+	}
+	for _, ex := range filter.ExcludeKeywords {
+		if containsKeyword(n.Keywords, ex) {
+			return false
+		}
+	}
+	if len(filter.Keywords) == 0 {
+		return true
+	}
+	for _, kw := range filter.Keywords {
+		if containsKeyword(n.Keywords, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMetadata проверяет фильтры канала по сведениям о проекте
+func matchesMetadata(filter dto.SinkFilter, n dto.Notification) bool {
+	if !containsAny(filter.Departments, n.Department) ||
+		!containsAny(filter.Procedures, n.Procedure) ||
+		!containsAny(filter.Kinds, n.Kind) {
+		return false
+	}
+	if len(filter.Okveds) == 0 {
+		return true
+	}
+	for _, want := range filter.Okveds {
+		want = strings.TrimSpace(want)
+		for _, code := range n.Okveds {
+			if code == want || strings.HasPrefix(code, want+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsAny проверяет, что value содержит одну из строк без учета регистра; пустой список подходит всегда
+func containsAny(options []string, value string) bool {
+	if len(options) == 0 {
+		return true
+	}
+	value = strings.ToLower(value)
+	for _, opt := range options {
+		if opt = strings.ToLower(strings.TrimSpace(opt)); opt != "" && strings.Contains(value, opt) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKeyword(keywords []string, kw string) bool {
+	kw = strings.ToLower(strings.TrimSpace(kw))
+	for _, k := range keywords {
+		if strings.ToLower(k) == kw {
+			return true
+		}
+	}
+	return false
+}
+
+// MultiNotifier рассылает совпадение во все каналы
+type MultiNotifier struct {
+	notifiers []Notifier
+}
+
+// NewMultiNotifier объединяет несколько каналов в один
+func NewMultiNotifier(notifiers ...Notifier) *MultiNotifier {
+	return &MultiNotifier{notifiers: notifiers}
+}
+
+func (m *MultiNotifier) Name() string {
+	names := make([]string, 0, len(m.notifiers))
+	for _, n := range m.notifiers {
+		names = append(names, n.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// Notify отправляет совпадение во все каналы; ошибка одного канала не мешает остальным
+func (m *MultiNotifier) Notify(ctx context.Context, n dto.Notification) error {
+	_, err := m.NotifySinks(ctx, n, nil)
+	return err
+}
+
+// NotifySinks отправляет совпадение во все каналы, кроме skip, и возвращает имена каналов,
+// в которые оно доставлено; ошибка одного канала не мешает остальным
+func (m *MultiNotifier) NotifySinks(ctx context.Context, n dto.Notification, skip map[string]bool) ([]string, error) {
+	var delivered []string
+	var errs []error
+	for _, notifier := range m.notifiers {
+		if skip[notifier.Name()] {
+			continue
+		}
+		if err := notifier.Notify(ctx, n); err != nil {
+			logger.Log.Errorf("❌ Канал %s: ошибка отправки уведомления для %s: %v", notifier.Name(), n.FileURL, err)
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.Name(), err))
+			continue
+		}
+		delivered = append(delivered, notifier.Name())
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// Flush отправляет накопленные совпадения во всех каналах, которые их копят
+func (m *MultiNotifier) Flush(ctx context.Context) error {
+	var errs []error
+	for _, notifier := range m.notifiers {
+		if err := FlushNotifier(ctx, notifier); err != nil {
+			logger.Log.Errorf("❌ Канал %s: ошибка отправки накопленных уведомлений: %v", notifier.Name(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Len возвращает количество каналов
+func (m *MultiNotifier) Len() int {
+	return len(m.notifiers)
+}
+
+// plainTextMessage формирует текст уведомления без разметки для email, Slack и Matrix
+func plainTextMessage(n dto.Notification) string {
+	text, err := renderTemplate(TemplateText, newTemplateData(n))
+	if err != nil {
+		logger.Log.Errorf("Ошибка шаблона %s: %v", TemplateText, err)
+		return "🔍 Найдено совпадение: " + n.FileURL
+	}
+	return text
+}
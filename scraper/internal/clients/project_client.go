@@ -0,0 +1,71 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// Адреса публичного JSON API стадий и файлов проекта
+const (
+	ProjectStagesURL = "https://regulation.gov.ru/api/public/PublicProjects/GetProjectStages/"
+	ProjectFileURL   = "https://regulation.gov.ru/api/public/Files/GetFile/"
+)
+
+// FetchProject загружает карточку проекта из JSON API (PROJECT_API_URL). Ответ может быть
+// как самим проектом, так и обернутым в {"result": {...}}
+func FetchProject(ctx context.Context, projectID string) (*dto.Project, error) {
+	resp, err := GetRegulation(ctx, config.GetProjectAPIURL()+projectID, "application/json, text/plain, */*")
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("карточка проекта %s: статус %s", projectID, resp.Status)
+	}
+
+	var wrapped struct {
+		Result *dto.Project `json:"result"`
+	}
+	if err := json.Unmarshal(b, &wrapped); err == nil && wrapped.Result != nil && wrapped.Result.Title != "" {
+		return wrapped.Result, nil
+	}
+	var p dto.Project
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("карточка проекта %s: %w", projectID, err)
+	}
+	if p.Title == "" {
+		return nil, errors.New("карточка проекта " + projectID + ": ответ без заголовка")
+	}
+	return &p, nil
+}
+
+// FetchProjectDetails загружает карточку проекта и его стадии с файлами. Ошибка означает,
+// что недоступна карточка; если недоступны только стадии, StagesKnown остается false
+func FetchProjectDetails(ctx context.Context, projectID string) (*dto.ProjectDetails, error) {
+	p, err := FetchProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	details := &dto.ProjectDetails{Project: *p}
+	stages, err := FetchProjectStages(ctx, projectID)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Log.Warnf("ошибка получения стадий проекта %s: %v", projectID, err)
+		}
+		return details, nil
+	}
+	details.Stages, details.StagesKnown = stages, true
+	return details, nil
+}
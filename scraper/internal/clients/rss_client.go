@@ -1,25 +1,24 @@
 package clients
 
 import (
+    "context"
     "encoding/xml"
+    "fmt"
     "io"
     "net/http"
 
     "github.com/notenoughtea/law_scraper/internal/dto"
 )
 
-func FetchRSS(url string) (*dto.RSS, error) {
-    req, err := http.NewRequest("GET", url, nil)
-    if err != nil {
-        return nil, err
-    }
-    req.Header.Set("Accept", "application/rss+xml, application/xml, */*")
-    client := &http.Client{}
-    resp, err := client.Do(req)
+func FetchRSS(ctx context.Context, url string) (*dto.RSS, error) {
+    resp, err := GetRegulation(ctx, url, "application/rss+xml, application/xml, */*")
     if err != nil {
         return nil, err
     }
     defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("RSS %s: статус %s", url, resp.Status)
+    }
     b, err := io.ReadAll(resp.Body)
     if err != nil {
         return nil, err
@@ -28,6 +27,7 @@ func FetchRSS(url string) (*dto.RSS, error) {
     if err := xml.Unmarshal(b, &feed); err != nil {
         return nil, err
     }
+    feed.ParseItems()
     return &feed, nil
 }
 
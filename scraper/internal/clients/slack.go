@@ -0,0 +1,55 @@
+package clients
+
+import (
+	"context"
+	"strings"
+
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// SlackNotifier отправляет совпадения во входящий вебхук Slack или Mattermost
+type SlackNotifier struct {
+	name string
+	cfg  dto.SlackSinkConfig
+}
+
+// NewSlackNotifier создает канал доставки для Slack/Mattermost
+func NewSlackNotifier(name string, cfg dto.SlackSinkConfig) *SlackNotifier {
+	return &SlackNotifier{name: name, cfg: cfg}
+}
+
+func (s *SlackNotifier) Name() string {
+	return s.name
+}
+
+type slackMessage struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Notify отправляет совпадение в формате mrkdwn, который понимают Slack и Mattermost
+func (s *SlackNotifier) Notify(ctx context.Context, n dto.Notification) error {
+	logger.Log.Infof("💬 Отправка совпадения %s в %s", n.FileURL, s.name)
+	return postJSON(ctx, s.cfg.WebhookURL, nil, slackMessage{
+		Text:     slackText(n),
+		Channel:  s.cfg.Channel,
+		Username: s.cfg.Username,
+	})
+}
+
+// slackText формирует текст со ссылками в формате <url|текст> по шаблону
+func slackText(n dto.Notification) string {
+	text, err := renderTemplate(TemplateSlack, newTemplateData(n))
+	if err != nil {
+		logger.Log.Errorf("Ошибка шаблона %s: %v", TemplateSlack, err)
+		return plainTextMessage(n)
+	}
+	return text
+}
+
+// slackEscape экранирует управляющие символы разметки Slack
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
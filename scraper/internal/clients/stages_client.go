@@ -1,63 +1,50 @@
 package clients
 
 import (
-    "encoding/json"
-    "io"
-    "net/http"
-    "regexp"
-)
-
-var uuidRe = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[1-5][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	"context"
+	"fmt"
+	"io"
+	"net/http"
 
-// FetchProjectStagesFileIDs получает JSON по GetProjectStages/{id} и извлекает все UUID-подобные идентификаторы файлов
-func FetchProjectStagesFileIDs(apiURL string) ([]string, error) {
-    req, err := http.NewRequest("GET", apiURL, nil)
-    if err != nil {
-        return nil, err
-    }
-    req.Header.Set("Accept", "application/json, text/plain, */*")
-    resp, err := http.DefaultClient.Do(req)
-    if err != nil {
-        return nil, err
-    }
-    defer resp.Body.Close()
-    b, err := io.ReadAll(resp.Body)
-    if err != nil {
-        return nil, err
-    }
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
 
-    var any interface{}
-    if err := json.Unmarshal(b, &any); err != nil {
-        return nil, err
-    }
-    seen := map[string]struct{}{}
-    var out []string
+// FetchProjectStages получает стадии проекта по GetProjectStages/{id} с файлами каждой стадии
+// (ID, исходное имя, размер, дата загрузки) и заполняет адреса загрузки файлов
+func FetchProjectStages(ctx context.Context, projectID string) ([]dto.ProjectStage, error) {
+	resp, err := GetRegulation(ctx, ProjectStagesURL+projectID, "application/json, text/plain, */*")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("стадии проекта: статус %s", resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 
-    var walk func(v interface{})
-    walk = func(v interface{}) {
-        switch t := v.(type) {
-        case map[string]interface{}:
-            for k, vv := range t {
-                // интересуют ключи, похожие на id/fileId, но также проверим все строки на UUID
-                if s, ok := vv.(string); ok {
-                    if uuidRe.MatchString(s) {
-                        if _, ok := seen[s]; !ok {
-                            seen[s] = struct{}{}
-                            out = append(out, s)
-                        }
-                    }
-                }
-                _ = k
-                walk(vv)
-            }
-        case []interface{}:
-            for _, it := range t {
-                walk(it)
-            }
-        }
-    }
-    walk(any)
-    return out, nil
+	stages, err := dto.ParseProjectStages(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(dto.StageFiles(stages)) == 0 {
+		// Структура ответа не распознана: берем все UUID, как раньше, чтобы не пропустить файлы
+		if ids := dto.CollectFileIDs(b); len(ids) > 0 {
+			logger.Log.Warnf("⚠️ Стадии проекта %s: файлы не найдены в известных полях, используем все UUID из ответа (%d)", projectID, len(ids))
+			fallback := dto.ProjectStage{}
+			for _, id := range ids {
+				fallback.Files = append(fallback.Files, dto.StageFile{ID: id})
+			}
+			stages = append(stages, fallback)
+		}
+	}
+	for i := range stages {
+		for j := range stages[i].Files {
+			stages[i].Files[j].URL = ProjectFileURL + stages[i].Files[j].ID
+		}
+	}
+	return stages, nil
 }
-
-
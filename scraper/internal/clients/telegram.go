@@ -2,14 +2,19 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
 	"github.com/notenoughtea/law_scraper/internal/logger"
 )
 
@@ -20,10 +25,14 @@ type TelegramMessage struct {
 }
 
 func SendTelegramMessage(message string) error {
+	return sendTelegramMessage(context.Background(), config.GetTelegramToken(), config.GetTelegramChatID(), message)
+}
+
+func sendTelegramMessage(ctx context.Context, token, chatID, message string) error {
 	logger.Log.Info("=== Начало отправки сообщения в Telegram ===")
 
-	token := config.GetTelegramToken()
-	chatID := config.GetTelegramChatID()
+	// Экранируем недопустимую разметку и обрезаем по лимиту, чтобы Telegram не отверг сообщение
+	message = FitTelegramHTML(message, telegramMessageLimit)
 
 	logger.Log.Infof("Проверка конфигурации: Token=%s, ChatID=%s",
 		maskToken(token), chatID)
@@ -54,7 +63,7 @@ func SendTelegramMessage(message string) error {
 	}
 	logger.Log.Infof("✓ Сообщение сериализовано, размер JSON: %d байт", len(jsonData))
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		logger.Log.Errorf("❌ Ошибка создания HTTP запроса: %v", err)
 		return fmt.Errorf("ошибка создания запроса: %w", err)
@@ -63,8 +72,7 @@ func SendTelegramMessage(message string) error {
 	logger.Log.Info("✓ HTTP запрос создан")
 
 	logger.Log.Info("Отправка запроса в Telegram API...")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := notifyHTTPClient.Do(req)
 	if err != nil {
 		logger.Log.Errorf("❌ Ошибка выполнения HTTP запроса: %v", err)
 		return fmt.Errorf("ошибка отправки сообщения: %w", err)
@@ -102,187 +110,104 @@ func maskToken(token string) string {
 	return token[:4] + "..." + token[len(token)-4:]
 }
 
-func SendFileURLWithKeywords(projectURL string, fileURL string, keywords []string, pubDate string, title string, description string) error {
-	logger.Log.Infof("📤 Подготовка отправки уведомления для файла: %s", fileURL)
-	logger.Log.Infof("Найдено ключевых слов: %d (%v)", len(keywords), keywords)
-	logger.Log.Infof("Дата публикации: %s", pubDate)
-	logger.Log.Infof("Заголовок: %s", title)
-
-	keywordsStr := ""
-	if len(keywords) > 0 {
-		keywordsStr = keywords[0]
-		for i := 1; i < len(keywords); i++ {
-			keywordsStr += ", " + keywords[i]
-		}
-	}
-
-	// Проверка: если ключевые слова не найдены, показываем предупреждение
-	if keywordsStr == "" {
-		keywordsStr = "не указаны"
-		logger.Log.Warnf("⚠️  Ключевые слова не переданы в уведомление для %s", fileURL)
-	}
-
-	projectSection := ""
-	if projectURL != "" {
-		projectSection = fmt.Sprintf("🌐 <b>Проект:</b> <a href=\"%s\">Открыть проект</a>\n\n", projectURL)
-	}
-	descLooksLikeProjectID := strings.Contains(strings.ToLower(description), "id проекта")
-	skipDescription := descLooksLikeProjectID && projectURL != ""
-
-	// Формируем caption для документа
-	// Важно: ключевые слова должны быть всегда, поэтому добавляем их сначала
-	caption := "🔍 <b>Найдено совпадение</b>\n\n"
+// TelegramNotifier доставляет совпадения в чат Telegram
+type TelegramNotifier struct {
+	name           string
+	token          string
+	chatID         string
+	sendAsDocument bool
+}
 
-	// Сначала добавляем ключевые слова (они важнее всего)
-	if keywordsStr != "" {
-		caption += fmt.Sprintf("🔑 <b>Ключевые слова:</b> %s\n\n", keywordsStr)
+// NewTelegramNotifier создает канал Telegram; незаданные настройки берутся из .env
+func NewTelegramNotifier(name string, cfg *dto.TelegramSinkConfig) *TelegramNotifier {
+	t := &TelegramNotifier{
+		name:           name,
+		token:          config.GetTelegramToken(),
+		chatID:         config.GetTelegramChatID(),
+		sendAsDocument: config.GetTelegramSendAsDocument(),
+	}
+	if cfg != nil {
+		if cfg.Token != "" {
+			t.token = cfg.Token
+		}
+		if cfg.ChatID != "" {
+			t.chatID = cfg.ChatID
+		}
+		if cfg.SendAsDocument != nil {
+			t.sendAsDocument = *cfg.SendAsDocument
+		}
 	}
+	return t
+}
 
-	if projectSection != "" {
-		caption += projectSection
-	}
+func (t *TelegramNotifier) Name() string {
+	return t.name
+}
 
-	if title != "" {
-		caption += fmt.Sprintf("📋 <b>%s</b>\n\n", title)
-	}
+func SendFileURLWithKeywords(projectURL string, fileURL string, keywords []string, pubDate string, title string, description string) error {
+	return NewTelegramNotifier("telegram", nil).Notify(context.Background(), dto.Notification{
+		ProjectURL:  projectURL,
+		FileURL:     fileURL,
+		Keywords:    keywords,
+		PubDate:     pubDate,
+		Title:       title,
+		Description: description,
+	})
+}
 
-	if description != "" && !skipDescription {
-		// Ограничиваем длину description до 30 символов
-		maxDescLen := 30
-		desc := description
-		if len(desc) > maxDescLen {
-			desc = desc[:maxDescLen] + "..."
-		}
-		caption += fmt.Sprintf("📝 %s\n\n", desc)
-	}
+// Notify формирует сообщение о совпадении и отправляет его файлом или ссылкой
+func (t *TelegramNotifier) Notify(ctx context.Context, n dto.Notification) error {
+	fileURL := n.FileURL
 
-	if pubDate != "" {
-		caption += fmt.Sprintf("📅 <b>Дата:</b> %s", pubDate)
-	}
+	logger.Log.Infof("📤 Подготовка отправки уведомления для файла: %s", fileURL)
+	logger.Log.Infof("Найдено ключевых слов: %d (%v)", len(n.Keywords), n.Keywords)
+	logger.Log.Infof("Дата публикации: %s", n.PubDate)
+	logger.Log.Infof("Заголовок: %s", n.Title)
 
-	// Ограничиваем общую длину caption до 1024 символов (лимит Telegram)
-	// Ключевые слова и ссылка на проект имеют приоритет
-	if len(caption) > 1024 {
-		keywordsSection := ""
-		if keywordsStr != "" {
-			keywordsSection = fmt.Sprintf("🔑 <b>Ключевые слова:</b> %s\n\n", keywordsStr)
+	// Проверяем режим отправки (отправлять ли файл напрямую)
+	if t.sendAsDocument {
+		logger.Log.Info("Режим: отправка файла как документ в Telegram")
+		// Отправляем файл напрямую как документ
+		err := sendTelegramDocument(ctx, t.token, t.chatID, n, BuildMatchCaption(n))
+		if err != nil && !errors.Is(err, ErrAttachmentTooLarge) && ctx.Err() != nil {
+			// Загрузка файла прервана остановкой сканирования - совпадение не теряем, отправляем ссылку.
+			// Короткий запрос ограничен таймаутом notifyHTTPClient
+			logger.Log.Warnf("⚠️ Файл %s не загружен (%v), отправляем ссылку", fileURL, err)
+			return t.sendLink(context.WithoutCancel(ctx), n, "")
 		}
-		projectSectionLimited := projectSection
-
-		// Пересобираем с ограничением title и description, но оставляем ключевые слова и проект
-		baseSize := len("🔍 <b>Найдено совпадение</b>\n\n") + len(keywordsSection) + len(projectSectionLimited) + 50 // 50 для форматирования и даты
-		maxAvailable := 1024 - baseSize - 100                                                                       // оставляем запас для даты и форматирования
-
-		if maxAvailable < 100 {
-			// Если совсем мало места, оставляем только ключевые слова и ссылку на проект
-			caption = "🔍 <b>Найдено совпадение</b>\n\n" + keywordsSection + projectSectionLimited
-			if pubDate != "" {
-				caption += fmt.Sprintf("📅 <b>Дата:</b> %s", pubDate)
-			}
-		} else {
-			// Пересобираем с ограничением title и description
-			caption = "🔍 <b>Найдено совпадение</b>\n\n" + keywordsSection + projectSectionLimited
-
-			if title != "" {
-				titleText := title
-				if len(titleText) > maxAvailable/2 {
-					titleText = titleText[:maxAvailable/2-3] + "..."
-				}
-				caption += fmt.Sprintf("📋 <b>%s</b>\n\n", titleText)
-				maxAvailable -= len(titleText)
-			}
-
-			if description != "" && maxAvailable > 30 && !skipDescription {
-				desc := description
-				if len(desc) > maxAvailable {
-					desc = desc[:maxAvailable-3] + "..."
-				} else if len(desc) > 30 {
-					desc = desc[:27] + "..."
-				}
-				caption += fmt.Sprintf("📝 %s\n\n", desc)
-			}
-
-			if pubDate != "" {
-				caption += fmt.Sprintf("📅 <b>Дата:</b> %s", pubDate)
-			}
-		}
-
-		// Финальная проверка: если все равно не помещается, оставляем только важные части
-		if len(caption) > 1024 {
-			caption = "🔍 <b>Найдено совпадение</b>\n\n" + keywordsSection + projectSectionLimited
-			if pubDate != "" {
-				datePart := fmt.Sprintf("📅 <b>Дата:</b> %s", pubDate)
-				if len(caption)+len(datePart) <= 1024 {
-					caption += datePart
-				}
-			}
-
-			if len(caption) > 1024 && keywordsStr != "" {
-				maxKeywordsLen := 1024 - len("🔍 <b>Найдено совпадение</b>\n\n🔑 <b>Ключевые слова:</b> \n\n") - len(projectSectionLimited) - 50
-				if maxKeywordsLen < 0 {
-					maxKeywordsLen = 0
-				}
-				if len(keywordsStr) > maxKeywordsLen && maxKeywordsLen > 3 {
-					keywordsStr = keywordsStr[:maxKeywordsLen-3] + "..."
-				}
-				keywordsSection = fmt.Sprintf("🔑 <b>Ключевые слова:</b> %s\n\n", keywordsStr)
-				caption = "🔍 <b>Найдено совпадение</b>\n\n" + keywordsSection + projectSectionLimited
-			}
+		if !errors.Is(err, ErrAttachmentTooLarge) {
+			return err
 		}
+		// Слишком большой файл отправляем ссылкой с пометкой
+		logger.Log.Warnf("⚠️ Файл %s не отправлен документом (%v), отправляем ссылку", fileURL, err)
+		return t.sendLink(ctx, n, "⚠️ Файл слишком большой для отправки в Telegram, скачайте его по ссылке.")
 	}
 
-	// Проверяем режим отправки (отправлять ли файл напрямую)
-	sendAsDocument := config.GetTelegramSendAsDocument()
+	return t.sendLink(ctx, n, "")
+}
 
-	if sendAsDocument {
-		logger.Log.Info("Режим: отправка файла как документ в Telegram")
-		// Отправляем файл напрямую как документ
-		return SendDocumentToTelegram(fileURL, caption)
-	}
+// sendLink отправляет сообщение о совпадении со ссылкой на файл; note добавляется в конец курсивом
+func (t *TelegramNotifier) sendLink(ctx context.Context, n dto.Notification, note string) error {
+	fileURL := n.FileURL
 
 	// Режим по умолчанию: отправка ссылки на файл
 	logger.Log.Info("Режим: отправка ссылки на файл")
 
-	message := "🔍 <b>Найдено совпадение</b>\n\n"
-
-	// Сначала добавляем ключевые слова (они важнее всего)
-	if keywordsStr != "" {
-		message += fmt.Sprintf("🔑 <b>Ключевые слова:</b> %s\n\n", keywordsStr)
-	}
-
-	if title != "" {
-		message += fmt.Sprintf("📋 <b>%s</b>\n\n", title)
-	}
-
-	message += fmt.Sprintf("📄 <b>Файл:</b> <a href=\"%s\">Скачать документ</a>\n", fileURL)
-
-	if projectSection != "" {
-		message += projectSection
-	}
-
-	if description != "" && !skipDescription {
-		// Ограничиваем длину description до 30 символов
-		maxDescLen := 30
-		desc := description
-		if len(desc) > maxDescLen {
-			desc = desc[:maxDescLen] + "..."
-		}
-		message += fmt.Sprintf("📝 %s\n\n", desc)
-	}
+	// Имя файла заполняет конвейер (clients.StageFileName); без него шаблон показывает
+	// «Скачать документ», а файл повторно не запрашивается
 
-	if pubDate != "" {
-		message += fmt.Sprintf("📅 <b>Дата:</b> %s", pubDate)
+	message, err := renderTemplate(TemplateTelegramMessage, newTemplateData(n))
+	if err != nil {
+		return fmt.Errorf("ошибка шаблона %s: %w", TemplateTelegramMessage, err)
 	}
 
-	// Добавляем инструкцию о расширении файла
-	if !hasExtension(fileURL) {
-		message += "\n\n💡 <i>После скачивания переименуйте файл, добавив расширение .docx</i>"
+	if note != "" {
+		message += "\n\n<i>" + EscapeHTML(note) + "</i>"
 	}
 
-	logger.Log.Infof("Сформированное сообщение для отправки (длина: %d символов)", len(message))
+	logger.Log.Infof("Сформированное сообщение для отправки (длина: %d символов)", utf8.RuneCountInString(message))
 
-	err := SendTelegramMessage(message)
-	if err != nil {
+	if err := sendTelegramMessage(ctx, t.token, t.chatID, message); err != nil {
 		logger.Log.Errorf("❌ Ошибка отправки уведомления для %s: %v", fileURL, err)
 		return err
 	}
@@ -291,87 +216,96 @@ func SendFileURLWithKeywords(projectURL string, fileURL string, keywords []strin
 	return nil
 }
 
-// hasExtension проверяет, есть ли расширение файла в URL
-func hasExtension(url string) bool {
-	// Проверяем наличие типичных расширений документов
-	extensions := []string{".docx", ".doc", ".pdf", ".txt", ".xlsx", ".xls"}
-	for _, ext := range extensions {
-		if len(url) >= len(ext) && url[len(url)-len(ext):] == ext {
-			return true
+// BuildMatchCaption формирует HTML-подпись о совпадении по шаблону (не длиннее 1024 символов
+// видимого текста в UTF-16, как считает Telegram).
+// Если подпись не помещается, последовательно сокращаются заголовок, описание и ключевые слова:
+// ключевые слова и ссылка на проект имеют приоритет
+func BuildMatchCaption(n dto.Notification) string {
+	data := newTemplateData(n)
+	shrink := []func(d *TemplateData){
+		func(d *TemplateData) {},
+		func(d *TemplateData) { d.TitleMaxLen = 300 },
+		func(d *TemplateData) { d.TitleMaxLen = 100; d.ShowDescription = false },
+		func(d *TemplateData) { d.Compact = true },
+		func(d *TemplateData) { d.KeywordsMaxLen = 500 },
+	}
+
+	var caption string
+	for _, step := range shrink {
+		step(&data)
+		rendered, err := renderTemplate(TemplateTelegramCaption, data)
+		if err != nil {
+			logger.Log.Errorf("Ошибка шаблона %s: %v", TemplateTelegramCaption, err)
+			return ""
+		}
+		caption = rendered
+		if TelegramTextLength(caption) <= telegramCaptionLimit {
+			break
 		}
 	}
-	return false
+	return FitTelegramHTML(caption, telegramCaptionLimit)
 }
 
 // SendDocumentToTelegram отправляет файл как документ в Telegram
 func SendDocumentToTelegram(fileURL string, caption string) error {
-	token := config.GetTelegramToken()
-	chatID := config.GetTelegramChatID()
-
-	if token == "" || chatID == "" {
-		return fmt.Errorf("telegram bot token или chat id не настроены")
-	}
+	return sendTelegramDocument(context.Background(), config.GetTelegramToken(), config.GetTelegramChatID(), dto.Notification{FileURL: fileURL}, caption)
+}
 
-	logger.Log.Infof("Скачивание файла с %s...", fileURL)
+// telegramUploadClient отправляет документы: большой файл загружается дольше обычного запроса,
+// поэтому запрос ограничен таймаутом загрузки файлов (DOWNLOAD_TIMEOUT_SECONDS)
+func telegramUploadClient() *http.Client {
+	return &http.Client{Timeout: config.GetDownloadTimeout()}
+}
 
-	// Скачиваем файл
-	resp, err := http.Get(fileURL)
-	if err != nil {
-		return fmt.Errorf("ошибка скачивания файла: %w", err)
+// telegramDocumentLimit возвращает максимальный размер файла для отправки документом
+func telegramDocumentLimit() int64 {
+	limit := config.GetTelegramMaxUploadBytes()
+	if budget := config.GetAttachmentMemoryBudgetBytes(); budget < limit {
+		limit = budget
 	}
-	defer resp.Body.Close()
+	return limit
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ошибка при скачивании файла: статус %d", resp.StatusCode)
+// sendTelegramDocument берет файл совпадения из кэша (или скачивает его) и потоково
+// отправляет его в чат под настоящим именем. Если файл больше лимита, возвращается ErrAttachmentTooLarge
+func sendTelegramDocument(ctx context.Context, token, chatID string, n dto.Notification, caption string) error {
+	if token == "" || chatID == "" {
+		return fmt.Errorf("telegram bot token или chat id не настроены")
 	}
 
-	fileData, err := io.ReadAll(resp.Body)
+	file, err := openAttachment(ctx, n, telegramDocumentLimit())
 	if err != nil {
-		return fmt.Errorf("ошибка чтения файла: %w", err)
+		return err
 	}
+	defer file.Close()
+	logger.Log.Infof("Файл готов к отправке: %s, размер: %d байт, тип: %s", file.Name, file.Size, file.ContentType)
 
-	logger.Log.Infof("Файл скачан, размер: %d байт", len(fileData))
+	caption = FitTelegramHTML(caption, telegramCaptionLimit)
 
 	// Отправляем файл в Telegram
 	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendDocument", token)
 
-	// Создаем multipart form
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// Добавляем chat_id
-	_ = writer.WriteField("chat_id", chatID)
+	// Форма пишется в pipe параллельно с отправкой запроса, поэтому файл не держится в памяти целиком
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeDocumentForm(writer, chatID, caption, file))
+	}()
 
-	// Добавляем caption
-	if caption != "" {
-		_ = writer.WriteField("caption", caption)
-		_ = writer.WriteField("parse_mode", "HTML")
-	}
-
-	// Добавляем файл
-	part, err := writer.CreateFormFile("document", "document.docx")
-	if err != nil {
-		return fmt.Errorf("ошибка создания form file: %w", err)
-	}
-
-	if _, err := part.Write(fileData); err != nil {
-		return fmt.Errorf("ошибка записи файла: %w", err)
-	}
-
-	writer.Close()
-
-	// Отправляем запрос
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, pr)
 	if err != nil {
+		pr.Close()
 		return fmt.Errorf("ошибка создания запроса: %w", err)
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
 	logger.Log.Info("Отправка документа в Telegram...")
 
-	apiResp, err := client.Do(req)
+	apiResp, err := telegramUploadClient().Do(req)
 	if err != nil {
+		if errors.Is(err, ErrAttachmentTooLarge) {
+			return err
+		}
 		return fmt.Errorf("ошибка отправки документа: %w", err)
 	}
 	defer apiResp.Body.Close()
@@ -380,9 +314,47 @@ func SendDocumentToTelegram(fileURL string, caption string) error {
 
 	if apiResp.StatusCode != http.StatusOK {
 		logger.Log.Errorf("❌ Ошибка Telegram API: %s, тело: %s", apiResp.Status, string(respBody))
+		if apiResp.StatusCode == http.StatusRequestEntityTooLarge {
+			return fmt.Errorf("%w: telegram api вернул %s", ErrAttachmentTooLarge, apiResp.Status)
+		}
 		return fmt.Errorf("telegram api вернул ошибку: %s", apiResp.Status)
 	}
 
 	logger.Log.Info("✅ Документ успешно отправлен в Telegram")
 	return nil
 }
+
+// writeDocumentForm пишет multipart-форму sendDocument: chat_id, подпись и сам файл
+func writeDocumentForm(writer *multipart.Writer, chatID, caption string, file *attachment) error {
+	if err := writer.WriteField("chat_id", chatID); err != nil {
+		return err
+	}
+	if caption != "" {
+		if err := writer.WriteField("caption", caption); err != nil {
+			return err
+		}
+		if err := writer.WriteField("parse_mode", "HTML"); err != nil {
+			return err
+		}
+	}
+
+	part, err := createFormFile(writer, "document", file.Name, file.ContentType)
+	if err != nil {
+		return fmt.Errorf("ошибка создания form file: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// createFormFile добавляет в multipart-форму файл с указанным именем и MIME-типом.
+// Имя передается в UTF-8 как есть: так его понимает Telegram Bot API
+func createFormFile(w *multipart.Writer, field, fileName, contentType string) (io.Writer, error) {
+	quote := strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quote.Replace(field), quote.Replace(fileName)))
+	h.Set("Content-Type", contentType)
+	return w.CreatePart(h)
+}
@@ -0,0 +1,217 @@
+package clients
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Лимиты Telegram считаются в UTF-16 единицах видимого текста (после разбора разметки)
+const (
+	telegramMessageLimit = 4096
+	telegramCaptionLimit = 1024
+)
+
+// telegramTags - теги, которые понимает Telegram в режиме parse_mode=HTML
+var telegramTags = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true,
+	"s": true, "strike": true, "del": true, "a": true, "code": true, "pre": true,
+	"tg-spoiler": true, "span": true, "blockquote": true,
+}
+
+var (
+	tagRe    = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9-]*)((?:\s+[a-zA-Z-]+="[^"<>]*")*)\s*>`)
+	entityRe = regexp.MustCompile(`^&(?:lt|gt|amp|quot|#[0-9]{1,7}|#x[0-9a-fA-F]{1,6});`)
+)
+
+// EscapeHTML экранирует текст для сообщений Telegram в режиме HTML
+func EscapeHTML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;").Replace(s)
+}
+
+// TelegramHTML собирает сообщение в HTML-разметке Telegram, экранируя весь переданный текст
+type TelegramHTML struct {
+	b strings.Builder
+}
+
+// Text добавляет обычный текст
+func (t *TelegramHTML) Text(s string) *TelegramHTML {
+	t.b.WriteString(EscapeHTML(s))
+	return t
+}
+
+// Bold добавляет жирный текст
+func (t *TelegramHTML) Bold(s string) *TelegramHTML {
+	t.b.WriteString("<b>" + EscapeHTML(s) + "</b>")
+	return t
+}
+
+// Italic добавляет курсив
+func (t *TelegramHTML) Italic(s string) *TelegramHTML {
+	t.b.WriteString("<i>" + EscapeHTML(s) + "</i>")
+	return t
+}
+
+// Code добавляет моноширинный текст
+func (t *TelegramHTML) Code(s string) *TelegramHTML {
+	t.b.WriteString("<code>" + EscapeHTML(s) + "</code>")
+	return t
+}
+
+// Link добавляет ссылку
+func (t *TelegramHTML) Link(url, text string) *TelegramHTML {
+	t.b.WriteString(`<a href="` + EscapeHTML(url) + `">` + EscapeHTML(text) + "</a>")
+	return t
+}
+
+// Line добавляет перевод строки
+func (t *TelegramHTML) Line() *TelegramHTML {
+	t.b.WriteByte('\n')
+	return t
+}
+
+// String возвращает сообщение, обрезанное до лимита длины сообщения Telegram
+func (t *TelegramHTML) String() string {
+	return FitTelegramHTML(t.b.String(), telegramMessageLimit)
+}
+
+// utf16Len возвращает длину строки в UTF-16 единицах, как ее считает Telegram
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// TelegramTextLength возвращает длину видимого текста HTML-сообщения в UTF-16 единицах
+func TelegramTextLength(s string) int {
+	n := 0
+	walkTelegramHTML(s, func(tok htmlToken) bool {
+		if !tok.tag {
+			n += utf16Len(tok.visible)
+		}
+		return true
+	})
+	return n
+}
+
+// htmlToken - фрагмент HTML: допустимый тег или текст (уже экранированный) с видимым значением
+type htmlToken struct {
+	tag     bool
+	closing bool
+	name    string
+	raw     string
+	visible string
+}
+
+// walkTelegramHTML разбирает строку на допустимые теги Telegram и текст.
+// Все, что не является допустимым тегом или сущностью, считается текстом и экранируется
+func walkTelegramHTML(s string, fn func(htmlToken) bool) {
+	for len(s) > 0 {
+		var tok htmlToken
+		switch s[0] {
+		case '<':
+			if m := tagRe.FindStringSubmatch(s); m != nil && telegramTags[strings.ToLower(m[2])] {
+				tok = htmlToken{tag: true, closing: m[1] == "/", name: strings.ToLower(m[2]), raw: m[0]}
+			} else {
+				tok = htmlToken{raw: "&lt;", visible: "<"}
+				s = s[1:]
+				if !fn(tok) {
+					return
+				}
+				continue
+			}
+		case '&':
+			if m := entityRe.FindString(s); m != "" {
+				tok = htmlToken{raw: m, visible: html.UnescapeString(m)}
+			} else {
+				tok = htmlToken{raw: "&amp;", visible: "&"}
+				s = s[1:]
+				if !fn(tok) {
+					return
+				}
+				continue
+			}
+		case '>':
+			tok = htmlToken{raw: "&gt;", visible: ">"}
+			s = s[1:]
+			if !fn(tok) {
+				return
+			}
+			continue
+		default:
+			r, size := utf8.DecodeRuneInString(s)
+			if r == utf8.RuneError && size == 1 {
+				// Невалидный байт UTF-8 Telegram не примет - пропускаем
+				s = s[1:]
+				continue
+			}
+			tok = htmlToken{raw: s[:size], visible: s[:size]}
+		}
+		s = s[len(tok.raw):]
+		if !fn(tok) {
+			return
+		}
+	}
+}
+
+// FitTelegramHTML приводит HTML к виду, который гарантированно разберет Telegram:
+// экранирует недопустимые теги и одиночные &, обрезает видимый текст до limit UTF-16 единиц
+// по границе символа, не разрывает теги и закрывает все открытые теги
+func FitTelegramHTML(s string, limit int) string {
+	total := TelegramTextLength(s)
+	budget := limit
+	truncated := total > limit
+	if truncated {
+		// место под многоточие
+		budget = limit - 1
+	}
+
+	var out strings.Builder
+	var open []string
+	used := 0
+	walkTelegramHTML(s, func(tok htmlToken) bool {
+		if tok.tag {
+			if tok.closing {
+				// закрываем только реально открытый тег
+				for i := len(open) - 1; i >= 0; i-- {
+					if open[i] == tok.name {
+						for j := len(open) - 1; j >= i; j-- {
+							out.WriteString("</" + open[j] + ">")
+						}
+						open = open[:i]
+						break
+					}
+				}
+				return true
+			}
+			if truncated && used >= budget {
+				return false
+			}
+			open = append(open, tok.name)
+			out.WriteString(tok.raw)
+			return true
+		}
+		n := utf16Len(tok.visible)
+		if used+n > budget {
+			return false
+		}
+		used += n
+		out.WriteString(tok.raw)
+		return true
+	})
+
+	if truncated {
+		out.WriteString("…")
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+	return out.String()
+}
@@ -0,0 +1,117 @@
+package clients
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFitTelegramHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		limit int
+		want  string
+	}{
+		{
+			name:  "помещается без изменений",
+			in:    "<b>жирный</b> текст",
+			limit: 100,
+			want:  "<b>жирный</b> текст",
+		},
+		{
+			name:  "обрезка по символам",
+			in:    "абвгдеж",
+			limit: 4,
+			want:  "абв…",
+		},
+		{
+			name:  "суррогатная пара не разрезается",
+			in:    "a😀b",
+			limit: 3,
+			want:  "a…",
+		},
+		{
+			name:  "эмодзи считаются за две единицы",
+			in:    "😀😀😀",
+			limit: 4,
+			want:  "😀…",
+		},
+		{
+			name:  "открытые теги закрываются",
+			in:    "<b>жирный <i>курсив</i> текст</b>",
+			limit: 8,
+			want:  "<b>жирный …</b>",
+		},
+		{
+			name:  "обрезка внутри вложенного тега",
+			in:    "<b>жирный <i>курсив</i></b>",
+			limit: 10,
+			want:  "<b>жирный <i>ку…</i></b>",
+		},
+		{
+			name:  "ссылка сохраняет атрибуты",
+			in:    `<a href="https://regulation.gov.ru/projects/1">ссылка на проект</a>`,
+			limit: 6,
+			want:  `<a href="https://regulation.gov.ru/projects/1">ссылк…</a>`,
+		},
+		{
+			name:  "сущность считается одним символом",
+			in:    "&amp;&amp;&amp;",
+			limit: 2,
+			want:  "&amp;…",
+		},
+		{
+			name:  "недопустимые теги и одиночный & экранируются",
+			in:    "<script>x</script> & y",
+			limit: 100,
+			want:  "&lt;script&gt;x&lt;/script&gt; &amp; y",
+		},
+		{
+			name:  "лишний закрывающий тег убирается",
+			in:    "текст</b>",
+			limit: 100,
+			want:  "текст",
+		},
+		{
+			name:  "незакрытый тег закрывается",
+			in:    "<b>текст",
+			limit: 100,
+			want:  "<b>текст</b>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FitTelegramHTML(tt.in, tt.limit)
+			if got != tt.want {
+				t.Errorf("FitTelegramHTML(%q, %d) = %q, ожидалось %q", tt.in, tt.limit, got, tt.want)
+			}
+			if n := TelegramTextLength(got); n > tt.limit {
+				t.Errorf("длина видимого текста %d больше лимита %d", n, tt.limit)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("результат содержит разрезанный символ: %q", got)
+			}
+		})
+	}
+}
+
+func TestTelegramTextLength(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"текст", 5},
+		{"😀", 2},
+		{"<b>жирный</b>", 6},
+		{`<a href="https://example.org">ссылка</a>`, 6},
+		{"&lt;b&gt;", 3},
+		{"a < b", 5},
+	}
+	for _, tt := range tests {
+		if got := TelegramTextLength(tt.in); got != tt.want {
+			t.Errorf("TelegramTextLength(%q) = %d, ожидалось %d", tt.in, got, tt.want)
+		}
+	}
+}
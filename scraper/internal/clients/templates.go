@@ -0,0 +1,150 @@
+package clients
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// Имена шаблонов уведомлений. Файл с таким именем в TEMPLATES_DIR заменяет встроенный шаблон
+const (
+	TemplateTelegramCaption = "telegram_caption.html.tmpl"
+	TemplateTelegramMessage = "telegram_message.html.tmpl"
+	TemplateEmail           = "email.html.tmpl"
+	TemplateText            = "text.txt.tmpl"
+	TemplateSlack           = "slack.txt.tmpl"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+var (
+	templatesMutex sync.RWMutex
+	templates      map[string]*template.Template
+)
+
+// templateFuncs - функции, доступные в шаблонах
+var templateFuncs = template.FuncMap{
+	"escape":      EscapeHTML,
+	"truncate":    truncateRunes,
+	"join":        strings.Join,
+	"nl2br":       func(s string) string { return strings.ReplaceAll(s, "\n", "<br>\n") },
+	"slackEscape": slackEscape,
+}
+
+// TemplateData - данные, передаваемые в шаблоны уведомлений
+type TemplateData struct {
+	dto.Notification
+
+	// KeywordsText - ключевые слова через запятую или "не указаны"
+	KeywordsText string
+	// ShowDescription - описание есть и это не набор полей "Поле: значение" из RSS,
+	// которые выводятся отдельно (номер, разработчик, процедура, вид)
+	ShowDescription bool
+	// Ограничения длины в символах; 0 - без ограничения
+	DescriptionMaxLen int
+	TitleMaxLen       int
+	KeywordsMaxLen    int
+	// Compact - оставить только самое важное (ключевые слова, ссылки, дату)
+	Compact bool
+}
+
+// newTemplateData подготавливает данные шаблона для совпадения
+func newTemplateData(n dto.Notification) TemplateData {
+	keywordsText := strings.Join(n.Keywords, ", ")
+	if keywordsText == "" {
+		keywordsText = "не указаны"
+		logger.Log.Warnf("⚠️  Ключевые слова не переданы в уведомление для %s", n.FileURL)
+	}
+	info := dto.ParseDescription(n.Description)
+	structured := info.Number != "" || info.Developer != "" || info.Procedure != "" || info.Kind != ""
+	return TemplateData{
+		Notification:      n,
+		KeywordsText:      keywordsText,
+		ShowDescription:   n.Description != "" && !structured,
+		DescriptionMaxLen: config.GetDescriptionMaxLen(),
+	}
+}
+
+// ReloadTemplates перечитывает шаблоны из TEMPLATES_DIR; отсутствующие или
+// некорректные файлы заменяются встроенными шаблонами
+func ReloadTemplates() {
+	dir := config.GetTemplatesDir()
+	names := []string{TemplateTelegramCaption, TemplateTelegramMessage, TemplateEmail, TemplateText, TemplateSlack}
+
+	loaded := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		if t, err := parseTemplateFile(filepath.Join(dir, name)); err == nil {
+			logger.Log.Infof("Шаблон %s загружен из %s", name, dir)
+			loaded[name] = t
+			continue
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			logger.Log.Warnf("Шаблон %s из %s не загружен: %v, используем встроенный", name, dir, err)
+		}
+
+		data, err := defaultTemplates.ReadFile("templates/" + name)
+		if err != nil {
+			logger.Log.Errorf("Встроенный шаблон %s не найден: %v", name, err)
+			continue
+		}
+		loaded[name] = template.Must(template.New(name).Funcs(templateFuncs).Parse(string(data)))
+	}
+
+	templatesMutex.Lock()
+	templates = loaded
+	templatesMutex.Unlock()
+}
+
+func parseTemplateFile(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(data))
+}
+
+// renderTemplate применяет шаблон к данным совпадения
+func renderTemplate(name string, data TemplateData) (string, error) {
+	templatesMutex.RLock()
+	loaded := templates
+	templatesMutex.RUnlock()
+	if loaded == nil {
+		ReloadTemplates()
+		templatesMutex.RLock()
+		loaded = templates
+		templatesMutex.RUnlock()
+	}
+
+	t, ok := loaded[name]
+	if !ok {
+		return "", errors.New("шаблон " + name + " не найден")
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// truncateRunes обрезает строку до max символов (не байт), добавляя многоточие; max <= 0 - без ограничения
+func truncateRunes(max int, s string) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	if max <= 1 {
+		return string(runes[:max])
+	}
+	return string(runes[:max-1]) + "…"
+}
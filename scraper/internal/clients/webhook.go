@@ -0,0 +1,265 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+)
+
+const (
+	// WebhookPayloadVersion - версия формата тела вебхука; увеличивается при несовместимых изменениях
+	WebhookPayloadVersion = 1
+
+	defaultSignatureHeader = "X-Law-Scraper-Signature"
+	defaultWebhookAttempts = 5
+	defaultDeadLetterFile  = "data/webhook_dead_letter.jsonl"
+)
+
+// notifyHTTPClient используется каналами доставки, работающими через HTTP
+var notifyHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// WebhookNotifier отправляет совпадения POST-запросом с подписанным JSON телом
+type WebhookNotifier struct {
+	name string
+	cfg  dto.WebhookSinkConfig
+}
+
+// WebhookPayload - тело запроса вебхука
+type WebhookPayload struct {
+	Version    int          `json:"version"`
+	Event      string       `json:"event"`
+	DeliveryID string       `json:"deliveryId"`
+	SentAt     time.Time    `json:"sentAt"`
+	Match      WebhookMatch `json:"match"`
+}
+
+// WebhookMatch - описание совпадения в теле вебхука
+type WebhookMatch struct {
+	ProjectID     string        `json:"projectId"`
+	ProjectNumber string        `json:"projectNumber,omitempty"`
+	ProjectURL    string        `json:"projectUrl"`
+	FileURL       string        `json:"fileUrl,omitempty"`
+	FileName      string        `json:"fileName,omitempty"`
+	Stage         string        `json:"stage,omitempty"`
+	Title         string        `json:"title"`
+	Description   string        `json:"description,omitempty"`
+	PubDate       string        `json:"pubDate,omitempty"`
+	Keywords      []string      `json:"keywords"`
+	Snippets      []string      `json:"snippets"`
+	Department    string        `json:"department,omitempty"`
+	Procedure     string        `json:"procedure,omitempty"`
+	Kind          string        `json:"kind,omitempty"`
+	Okveds        []string      `json:"okveds,omitempty"`
+	Deadlines     dto.Deadlines `json:"deadlines"`
+}
+
+// webhookDeadLetter - запись о сообщении, которое не удалось доставить
+type webhookDeadLetter struct {
+	Sink     string          `json:"sink"`
+	URL      string          `json:"url"`
+	FailedAt time.Time       `json:"failedAt"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// errPermanent помечает ошибки, при которых повторять запрос бессмысленно
+var errPermanent = errors.New("постоянная ошибка")
+
+// NewWebhookNotifier создает канал доставки через произвольный вебхук
+func NewWebhookNotifier(name string, cfg dto.WebhookSinkConfig) *WebhookNotifier {
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = defaultSignatureHeader
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultWebhookAttempts
+	}
+	if cfg.RetryDelaySeconds <= 0 {
+		cfg.RetryDelaySeconds = 2
+	}
+	if cfg.DeadLetterFile == "" {
+		cfg.DeadLetterFile = defaultDeadLetterFile
+	}
+	return &WebhookNotifier{name: name, cfg: cfg}
+}
+
+func (w *WebhookNotifier) Name() string {
+	return w.name
+}
+
+// Notify отправляет совпадение в вебхук с повторами; после исчерпания попыток
+// сообщение записывается в dead-letter файл
+func (w *WebhookNotifier) Notify(ctx context.Context, n dto.Notification) error {
+	payload := WebhookPayload{
+		Version:    WebhookPayloadVersion,
+		Event:      "match",
+		DeliveryID: newDeliveryID(),
+		SentAt:     time.Now().UTC(),
+		Match: WebhookMatch{
+			ProjectID:     n.ProjectID,
+			ProjectNumber: n.ProjectNumber,
+			ProjectURL:    n.ProjectURL,
+			Title:         n.Title,
+			Description:   n.Description,
+			PubDate:       n.PubDate,
+			Keywords:      n.Keywords,
+			Snippets:      n.Snippets,
+			Department:    n.Department,
+			Procedure:     n.Procedure,
+			Kind:          n.Kind,
+			Okveds:        n.Okveds,
+			Deadlines:     n.Deadlines,
+		},
+	}
+	if n.IsFile() {
+		payload.Match.FileURL = n.FileURL
+		payload.Match.FileName = n.FileName
+		payload.Match.Stage = n.Stage
+	}
+	if payload.Match.Snippets == nil {
+		payload.Match.Snippets = []string{}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации: %w", err)
+	}
+
+	logger.Log.Infof("🔗 Отправка совпадения %s в вебхук %s (delivery %s)", n.FileURL, w.name, payload.DeliveryID)
+
+	delay := time.Duration(w.cfg.RetryDelaySeconds) * time.Second
+	attempt := 1
+	for ; ; attempt++ {
+		err = w.deliver(ctx, payload.DeliveryID, body)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, errPermanent) || attempt >= w.cfg.MaxAttempts {
+			break
+		}
+		logger.Log.Warnf("Вебхук %s: попытка %d/%d не удалась: %v, повтор через %s",
+			w.name, attempt, w.cfg.MaxAttempts, err, delay)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		if ctx.Err() != nil {
+			// Отправка остановлена - сообщение не теряем, оно уходит в dead-letter файл
+			err = fmt.Errorf("повтор отменен (%w): %v", ctx.Err(), err)
+			break
+		}
+		delay *= 2
+	}
+
+	if dlErr := repository.AppendDeadLetter(w.cfg.DeadLetterFile, webhookDeadLetter{
+		Sink:     w.name,
+		URL:      w.cfg.URL,
+		FailedAt: time.Now().UTC(),
+		Attempts: attempt,
+		Error:    err.Error(),
+		Payload:  body,
+	}); dlErr != nil {
+		logger.Log.Errorf("❌ Не удалось записать сообщение в dead-letter файл %s: %v", w.cfg.DeadLetterFile, dlErr)
+	} else {
+		logger.Log.Warnf("Вебхук %s: сообщение %s записано в %s", w.name, payload.DeliveryID, w.cfg.DeadLetterFile)
+	}
+	return err
+}
+
+// deliver выполняет одну попытку доставки подписанного тела
+func (w *WebhookNotifier) deliver(ctx context.Context, deliveryID string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: ошибка создания запроса: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Law-Scraper-Event", "match")
+	req.Header.Set("X-Law-Scraper-Delivery", deliveryID)
+	req.Header.Set("X-Law-Scraper-Version", strconv.Itoa(WebhookPayloadVersion))
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.cfg.Secret != "" {
+		req.Header.Set(w.cfg.SignatureHeader, SignWebhookBody(w.cfg.Secret, body))
+	}
+
+	resp, err := notifyHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка отправки запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("сервер вернул ошибку: %s, тело ответа: %s", resp.Status, string(respBody))
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout {
+		return err
+	}
+	return fmt.Errorf("%w: %v", errPermanent, err)
+}
+
+// SignWebhookBody возвращает подпись тела в формате "sha256=<hex>".
+// Получатель должен вычислить HMAC-SHA256 от сырого тела запроса с тем же секретом и сравнить
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDeliveryID генерирует уникальный идентификатор доставки
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// postJSON отправляет тело в формате JSON и проверяет код ответа
+func postJSON(ctx context.Context, url string, headers map[string]string, payload interface{}) error {
+	return sendJSON(ctx, http.MethodPost, url, headers, payload)
+}
+
+func sendJSON(ctx context.Context, method, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := notifyHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка отправки запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("сервер вернул ошибку: %s, тело ответа: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 var projectRoot string
@@ -49,7 +50,12 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
+// GetProjectRoot возвращает корень проекта; PROJECT_ROOT проверяется при каждом вызове,
+// чтобы каталог данных можно было подменить и после запуска (например, в тестах)
 func GetProjectRoot() string {
+	if root := os.Getenv("PROJECT_ROOT"); root != "" {
+		return root
+	}
 	return projectRoot
 }
 
@@ -130,3 +136,342 @@ func GetMaxWorkers() int {
 	}
 	return 0 // вернет 0 если не установлено - будет использовано значение по умолчанию
 }
+
+// GetNotifiersFilePath возвращает путь к файлу каналов доставки (NOTIFIERS_FILE, по умолчанию data/notifiers.json)
+func GetNotifiersFilePath() string {
+	if p := os.Getenv("NOTIFIERS_FILE"); p != "" {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(projectRoot, p)
+	}
+	return filepath.Join(projectRoot, "data", "notifiers.json")
+}
+
+// GetTemplatesDir возвращает каталог с пользовательскими шаблонами уведомлений
+func GetTemplatesDir() string {
+	if p := os.Getenv("TEMPLATES_DIR"); p != "" {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(projectRoot, p)
+	}
+	return filepath.Join(projectRoot, "data", "templates")
+}
+
+// GetDescriptionMaxLen возвращает максимальную длину описания в уведомлении (в символах)
+func GetDescriptionMaxLen() int {
+	if v := os.Getenv("DESCRIPTION_MAX_LEN"); v != "" {
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 30
+}
+
+// GetAttachmentCacheDir возвращает каталог кэша скачанных вложений
+func GetAttachmentCacheDir() string {
+	if p := os.Getenv("ATTACHMENT_CACHE_DIR"); p != "" {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(projectRoot, p)
+	}
+	return filepath.Join(projectRoot, "data", "attachments")
+}
+
+// GetAttachmentCacheMaxBytes возвращает максимальный размер кэша вложений (ATTACHMENT_CACHE_MAX_MB, по умолчанию 200 МБ).
+// 0 отключает кэш
+func GetAttachmentCacheMaxBytes() int64 {
+	if v := os.Getenv("ATTACHMENT_CACHE_MAX_MB"); v != "" {
+		var mb int64
+		if _, err := fmt.Sscanf(v, "%d", &mb); err == nil && mb >= 0 {
+			return mb << 20
+		}
+	}
+	return 200 << 20
+}
+
+// GetTelegramMaxUploadBytes возвращает лимит размера документа для загрузки в Telegram
+// (TELEGRAM_MAX_UPLOAD_MB, по умолчанию 50 МБ - лимит Bot API)
+func GetTelegramMaxUploadBytes() int64 {
+	if v := os.Getenv("TELEGRAM_MAX_UPLOAD_MB"); v != "" {
+		var mb int64
+		if _, err := fmt.Sscanf(v, "%d", &mb); err == nil && mb > 0 {
+			return mb << 20
+		}
+	}
+	return 50 << 20
+}
+
+// GetAttachmentMemoryBudgetBytes возвращает максимальный размер файла, который разрешено
+// обрабатывать при отправке вложением (ATTACHMENT_MEMORY_MB, по умолчанию 50 МБ).
+// Файлы больше бюджета отправляются ссылкой
+func GetAttachmentMemoryBudgetBytes() int64 {
+	if v := os.Getenv("ATTACHMENT_MEMORY_MB"); v != "" {
+		var mb int64
+		if _, err := fmt.Sscanf(v, "%d", &mb); err == nil && mb > 0 {
+			return mb << 20
+		}
+	}
+	return 50 << 20
+}
+
+// GetRequestTimeout возвращает таймаут одного HTTP-запроса к regulation.gov.ru
+// (REQUEST_TIMEOUT_SECONDS, по умолчанию 120 секунд)
+func GetRequestTimeout() time.Duration {
+	if v := os.Getenv("REQUEST_TIMEOUT_SECONDS"); v != "" {
+		var sec int
+		if _, err := fmt.Sscanf(v, "%d", &sec); err == nil && sec > 0 {
+			return time.Duration(sec) * time.Second
+		}
+	}
+	return 120 * time.Second
+}
+
+// GetDownloadTimeout возвращает, сколько можно читать тело ответа regulation.gov.ru после получения
+// заголовков (DOWNLOAD_TIMEOUT_SECONDS, по умолчанию 900 секунд)
+func GetDownloadTimeout() time.Duration {
+	if v := os.Getenv("DOWNLOAD_TIMEOUT_SECONDS"); v != "" {
+		var sec int
+		if _, err := fmt.Sscanf(v, "%d", &sec); err == nil && sec > 0 {
+			return time.Duration(sec) * time.Second
+		}
+	}
+	return 900 * time.Second
+}
+
+// GetScanTimeout возвращает максимальную длительность одного сканирования
+// (SCAN_TIMEOUT_MINUTES, по умолчанию 180 минут, 0 - без ограничения)
+func GetScanTimeout() time.Duration {
+	if v := os.Getenv("SCAN_TIMEOUT_MINUTES"); v != "" {
+		var min int
+		if _, err := fmt.Sscanf(v, "%d", &min); err == nil && min >= 0 {
+			return time.Duration(min) * time.Minute
+		}
+	}
+	return 180 * time.Minute
+}
+
+// HTTPSettings - настройки общего HTTP-клиента для API regulation.gov.ru
+type HTTPSettings struct {
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	UserAgent      string
+	// Proxy - URL прокси; пустая строка - прокси из HTTP_PROXY/HTTPS_PROXY
+	Proxy string
+}
+
+// GetHTTPSettings возвращает настройки HTTP-клиента для regulation.gov.ru
+func GetHTTPSettings() HTTPSettings {
+	s := HTTPSettings{
+		ConnectTimeout: 10 * time.Second,
+		ReadTimeout:    60 * time.Second,
+		MaxRetries:     3,
+		RetryBaseDelay: time.Second,
+		UserAgent:      "Mozilla/5.0 (compatible; law_scraper/1.0; +https://github.com/notenoughtea/law_scraper)",
+		Proxy:          os.Getenv("REGULATION_PROXY"),
+	}
+	var n int
+	if _, err := fmt.Sscanf(os.Getenv("HTTP_CONNECT_TIMEOUT_SECONDS"), "%d", &n); err == nil && n > 0 {
+		s.ConnectTimeout = time.Duration(n) * time.Second
+	}
+	if _, err := fmt.Sscanf(os.Getenv("HTTP_READ_TIMEOUT_SECONDS"), "%d", &n); err == nil && n > 0 {
+		s.ReadTimeout = time.Duration(n) * time.Second
+	}
+	if _, err := fmt.Sscanf(os.Getenv("HTTP_MAX_RETRIES"), "%d", &n); err == nil && n >= 0 {
+		s.MaxRetries = n
+	}
+	if _, err := fmt.Sscanf(os.Getenv("HTTP_RETRY_BASE_MS"), "%d", &n); err == nil && n > 0 {
+		s.RetryBaseDelay = time.Duration(n) * time.Millisecond
+	}
+	if ua := os.Getenv("HTTP_USER_AGENT"); ua != "" {
+		s.UserAgent = ua
+	}
+	return s
+}
+
+// GetHostRateLimit возвращает ограничение числа запросов в секунду к одному хосту
+// (HOST_RPS, по умолчанию 2; 0 - без ограничения)
+func GetHostRateLimit() float64 {
+	if v := os.Getenv("HOST_RPS"); v != "" {
+		var rps float64
+		if _, err := fmt.Sscanf(v, "%g", &rps); err == nil && rps >= 0 {
+			return rps
+		}
+	}
+	return 2
+}
+
+// GetHostMaxInFlight возвращает максимальное число одновременных запросов к одному хосту
+// (HOST_MAX_INFLIGHT, по умолчанию 4)
+func GetHostMaxInFlight() int {
+	if v := os.Getenv("HOST_MAX_INFLIGHT"); v != "" {
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 4
+}
+
+// GetStageWorkers возвращает число обработчиков стадии конвейера сканирования
+// из PIPELINE_<STAGE>_WORKERS (например, PIPELINE_PAGE_WORKERS) или значение по умолчанию
+func GetStageWorkers(stage string, def int) int {
+	if v := os.Getenv("PIPELINE_" + strings.ToUpper(stage) + "_WORKERS"); v != "" {
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// GetScanMemoryBytes возвращает общий бюджет памяти под скачанные файлы во время сканирования
+// (SCAN_MEMORY_MB, по умолчанию 128 МБ). Воркеры загрузки резервируют из него размер файла
+// перед скачиванием
+func GetScanMemoryBytes() int64 {
+	if v := os.Getenv("SCAN_MEMORY_MB"); v != "" {
+		var mb int64
+		if _, err := fmt.Sscanf(v, "%d", &mb); err == nil && mb > 0 {
+			return mb << 20
+		}
+	}
+	return 128 << 20
+}
+
+// GetScanSpoolDir возвращает каталог временных файлов для вложений, которые не помещаются
+// в бюджет памяти (SCAN_SPOOL_DIR, по умолчанию data/tmp)
+func GetScanSpoolDir() string {
+	if p := os.Getenv("SCAN_SPOOL_DIR"); p != "" {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(projectRoot, p)
+	}
+	return filepath.Join(projectRoot, "data", "tmp")
+}
+
+// GetBackfillMaxPages возвращает, сколько страниц списка проектов просматривает
+// ретроспективное сканирование (BACKFILL_MAX_PAGES, по умолчанию 100)
+func GetBackfillMaxPages() int {
+	if v := os.Getenv("BACKFILL_MAX_PAGES"); v != "" {
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 100
+}
+
+// GetActsPageSize возвращает размер страницы при обходе списка проектов (ACTS_PAGE_SIZE, по умолчанию 20)
+func GetActsPageSize() int {
+	if v := os.Getenv("ACTS_PAGE_SIZE"); v != "" {
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 20
+}
+
+// GetActsMaxPages возвращает, сколько страниц списка проектов можно просмотреть за один обход
+// (ACTS_MAX_PAGES, по умолчанию 50)
+func GetActsMaxPages() int {
+	if v := os.Getenv("ACTS_MAX_PAGES"); v != "" {
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 50
+}
+
+// GetTextStoreDir возвращает каталог хранилища извлеченных текстов документов
+// (TEXT_STORE_DIR, по умолчанию data/texts)
+func GetTextStoreDir() string {
+	if p := os.Getenv("TEXT_STORE_DIR"); p != "" {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(projectRoot, p)
+	}
+	return filepath.Join(projectRoot, "data", "texts")
+}
+
+// GetTextStoreDays возвращает, сколько дней хранить извлеченные тексты
+// (TEXT_STORE_DAYS, по умолчанию 90; 0 отключает хранение)
+func GetTextStoreDays() int {
+	if v := os.Getenv("TEXT_STORE_DAYS"); v != "" {
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return 90
+}
+
+// GetTextStoreMaxBytes возвращает, сколько текста одного документа сохранять в хранилище и индексе
+// (TEXT_STORE_MAX_MB, по умолчанию 8 МБ); остаток текста не сохраняется
+func GetTextStoreMaxBytes() int64 {
+	if v := os.Getenv("TEXT_STORE_MAX_MB"); v != "" {
+		var mb int64
+		if _, err := fmt.Sscanf(v, "%d", &mb); err == nil && mb > 0 {
+			return mb << 20
+		}
+	}
+	return 8 << 20
+}
+
+// GetSearchIndexDir возвращает каталог полнотекстового индекса документов
+// (SEARCH_INDEX_DIR, по умолчанию data/search)
+func GetSearchIndexDir() string {
+	if p := os.Getenv("SEARCH_INDEX_DIR"); p != "" {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(projectRoot, p)
+	}
+	return filepath.Join(projectRoot, "data", "search")
+}
+
+// GetAPIAddr возвращает адрес HTTP API (API_ADDR, например ":8080"); пусто - API выключен
+func GetAPIAddr() string {
+	return os.Getenv("API_ADDR")
+}
+
+// GetProjectAPIURL возвращает адрес карточки проекта в JSON API сайта, к которому добавляется ID проекта
+// (PROJECT_API_URL, по умолчанию https://regulation.gov.ru/api/public/PublicProjects/GetProject/)
+func GetProjectAPIURL() string {
+	if v := os.Getenv("PROJECT_API_URL"); v != "" {
+		return v
+	}
+	return "https://regulation.gov.ru/api/public/PublicProjects/GetProject/"
+}
+
+// GetMatchLogMaxMB возвращает размер журнала совпадений, после которого он ротируется
+// (MATCH_LOG_MAX_MB, по умолчанию 10)
+func GetMatchLogMaxMB() int {
+	if v := os.Getenv("MATCH_LOG_MAX_MB"); v != "" {
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 10
+}
+
+// GetMatchLogKeep возвращает, сколько ротированных частей журнала совпадений хранить
+// (MATCH_LOG_KEEP, по умолчанию 5; 0 - хранить все)
+func GetMatchLogKeep() int {
+	if v := os.Getenv("MATCH_LOG_KEEP"); v != "" {
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return 5
+}
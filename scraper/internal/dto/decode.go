@@ -0,0 +1,156 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// siteDateLayouts - форматы дат, которые встречаются в API regulation.gov.ru
+var siteDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006",
+}
+
+// ParseSiteDate разбирает дату в одном из форматов сайта; даты без зоны считаются местными
+func ParseSiteDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range siteDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SiteTime - дата из API сайта. null, пустая строка и нераспознанный формат дают нулевое время
+type SiteTime struct {
+	time.Time
+}
+
+func (t *SiteTime) UnmarshalJSON(data []byte) error {
+	t.Time = time.Time{}
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		t.Time, _ = ParseSiteDate(s)
+	}
+	return nil
+}
+
+func (t SiteTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Format(time.RFC3339))
+}
+
+// NamedList - список названий (ключевые слова, разработчики). Сайт отдает его то строкой
+// через запятую, то массивом строк, то массивом объектов - из объектов берется название
+type NamedList []string
+
+// namedListFields - поля объекта, в которых ищется название, по приоритету
+var namedListFields = []string{"description", "name", "title", "fullName", "shortName", "value"}
+
+func (l *NamedList) UnmarshalJSON(data []byte) error {
+	*l = nil
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+			if part = strings.TrimSpace(part); part != "" {
+				*l = append(*l, part)
+			}
+		}
+		return nil
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(data, &items) != nil {
+		return nil
+	}
+	for _, item := range items {
+		if json.Unmarshal(item, &s) == nil {
+			if s = strings.TrimSpace(s); s != "" {
+				*l = append(*l, s)
+			}
+			continue
+		}
+		var obj map[string]any
+		if json.Unmarshal(item, &obj) != nil {
+			continue
+		}
+		for _, key := range namedListFields {
+			if name, ok := obj[key].(string); ok && strings.TrimSpace(name) != "" {
+				*l = append(*l, strings.TrimSpace(name))
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// decodeTolerant разбирает JSON-объект в структуру dst (указатель на тип без собственного
+// UnmarshalJSON). Если разбор целиком не удался, поля разбираются по одному: число в строковом
+// поле становится строкой, число в кавычках - числом, а остальные неподходящие поля остаются пустыми
+func decodeTolerant(data []byte, dst any) error {
+	if err := json.Unmarshal(data, dst); err == nil {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	byName := make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		byName[strings.ToLower(k)] = v
+	}
+
+	rv := reflect.ValueOf(dst).Elem()
+	rv.Set(reflect.Zero(rv.Type()))
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		raw, ok := byName[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if json.Unmarshal(raw, fv.Addr().Interface()) == nil {
+			continue
+		}
+		fv.Set(reflect.Zero(fv.Type()))
+		coerceField(fv, bytes.TrimSpace(raw))
+	}
+	return nil
+}
+
+// coerceField записывает в строковое или числовое поле значение другого скалярного типа
+func coerceField(fv reflect.Value, raw []byte) {
+	var s string
+	quoted := json.Unmarshal(raw, &s) == nil
+	switch fv.Kind() {
+	case reflect.String:
+		if !quoted && len(raw) > 0 && (raw[0] == '-' || raw[0] >= '0' && raw[0] <= '9') {
+			fv.SetString(string(raw))
+		}
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); quoted && err == nil {
+			fv.SetInt(n)
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(strings.TrimSpace(s)); quoted && err == nil {
+			fv.SetBool(b)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"strings"
+	"time"
+)
+
+// ProjectInfo - сведения о проекте из описания элемента RSS. Описание состоит из строк
+// "Поле: значение", например:
+//
+//	ID проекта: 01/02/10-25/00161862
+//	Дата создания: 30 October 2025
+//	Разработчик: "Парамонов Алексей Игоревич"
+//	Процедура: "Раскрытие информации о подготовке проектов нормативных правовых актов"
+//	Вид: "Проект ведомственного акта"
+type ProjectInfo struct {
+	// Number - полный номер проекта ("ID проекта")
+	Number string
+	// Created - дата создания проекта (нулевая, если не указана или не разобрана)
+	Created time.Time
+	// Developer - разработчик: в RSS обычно ответственный сотрудник, в списке проектов - орган власти
+	Developer string
+	Procedure string
+	// Kind - вид акта ("Вид")
+	Kind   string
+	Okveds []string
+}
+
+// ParseDescription разбирает описание элемента RSS; неизвестные строки пропускаются
+func ParseDescription(description string) ProjectInfo {
+	var info ProjectInfo
+	for _, line := range strings.Split(description, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = unquote(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "id проекта":
+			info.Number = value
+		case "дата создания":
+			info.Created = parseCreationDate(value)
+		case "разработчик":
+			info.Developer = value
+		case "процедура":
+			info.Procedure = value
+		case "вид":
+			info.Kind = value
+		case "оквэд":
+			for _, code := range strings.Split(value, ",") {
+				if code = unquote(code); code != "" {
+					info.Okveds = append(info.Okveds, code)
+				}
+			}
+		}
+	}
+	return info
+}
+
+// parseCreationDate разбирает дату создания: в RSS она вида "30 October 2025",
+// в описаниях из списка проектов - в форматах сайта
+func parseCreationDate(s string) time.Time {
+	if t, err := time.ParseInLocation("2 January 2006", s, time.Local); err == nil {
+		return t
+	}
+	t, _ := ParseSiteDate(s)
+	return t
+}
+
+// unquote убирает пробелы и кавычки вокруг значения
+func unquote(s string) string {
+	return strings.Trim(strings.TrimSpace(s), `"«»`)
+}
@@ -1,65 +1,159 @@
 package dto
 
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ListResponse - страница публичного списка проектов regulation.gov.ru
 type ListResponse struct {
-	Result []struct {
-		ID                  string `json:"id"`
-		DevelopedDepartment struct {
-			ImageID     string `json:"imageId"`
-			ID          string `json:"id"`
-			Description string `json:"description"`
-		} `json:"developedDepartment"`
-		DeveloperUser                     interface{} `json:"developerUser"`
-		ResponsibleEmployee               interface{} `json:"responsibleEmployee"`
-		ProjectID                         string      `json:"projectId"`
-		CreationDate                      string      `json:"creationDate"`
-		Title                             string      `json:"title"`
-		Stage                             string      `json:"stage"`
-		Status                            string      `json:"status"`
-		RegulatoryImpact                  string      `json:"regulatoryImpact"`
-		StartPublicDiscussion             string      `json:"startPublicDiscussion"`
-		EndPublicDiscussion               string      `json:"endPublicDiscussion"`
-		StartParallelPublicDiscussion     interface{} `json:"startParallelPublicDiscussion"`
-		EndParallelPublicDiscussion       interface{} `json:"endParallelPublicDiscussion"`
-		Deadline                          interface{} `json:"deadline"`
-		PublicationDate                   interface{} `json:"publicationDate"`
-		StartPublicNotificationDiscussion interface{} `json:"startPublicNotificationDiscussion"`
-		EndPublicNotificationDiscussion   interface{} `json:"endPublicNotificationDiscussion"`
-		StartPublicTextDiscussion         interface{} `json:"startPublicTextDiscussion"`
-		EndPublicTextDiscussion           interface{} `json:"endPublicTextDiscussion"`
-		ImportantForRegions               bool        `json:"importantForRegions"`
-		SupervisoryActivities             bool        `json:"supervisoryActivities"`
-		StartPublicGradeConsultations     interface{} `json:"startPublicGradeConsultations"`
-		EndPublicGradeConsultations       interface{} `json:"endPublicGradeConsultations"`
-		Guillotine                        bool        `json:"guillotine"`
-		Procedure                         struct {
-			ID          string `json:"id"`
-			Description string `json:"description"`
-		} `json:"procedure"`
-		ProjectType interface{} `json:"projectType"`
-		KeyWords    interface{} `json:"keyWords"`
-		Okveds      []struct {
-			ImageID     string `json:"imageId"`
-			ID          string `json:"id"`
-			Description string `json:"description"`
-		} `json:"okveds"`
-		Workflow             interface{} `json:"workflow"`
-		NotificationList     interface{} `json:"notificationList"`
-		ContactList          interface{} `json:"contactList"`
-		Developers           interface{} `json:"developers"`
-		ReasonForDevelopment interface{} `json:"reasonForDevelopment"`
-		LinkedNpa            interface{} `json:"linkedNpa"`
-		Hidden               bool        `json:"hidden"`
-		IsOrv                interface{} `json:"isOrv"`
-		NpaStatistics        struct {
-			Views       int    `json:"views"`
-			Rating      int    `json:"rating"`
-			Comments    int    `json:"comments"`
-			Description string `json:"description"`
-		} `json:"npaStatistics"`
-		NpaDiscussionStat interface{} `json:"npaDiscussionStat"`
-		NpaRatings        interface{} `json:"npaRatings"`
-	} `json:"result"`
-	Count      int `json:"count"`
-	TotalCount int `json:"totalCount"`
-	Page       int `json:"page"`
+	Result     []Project `json:"result"`
+	Count      int       `json:"count"`
+	TotalCount int       `json:"totalCount"`
+	Page       int       `json:"page"`
+}
+
+func (r *ListResponse) UnmarshalJSON(data []byte) error {
+	type plain ListResponse
+	return decodeTolerant(data, (*plain)(r))
+}
+
+// Project - проект нормативного акта из списка. Поля, которые не запрошены в orderedFields,
+// приходят пустыми (null). Даты разбираются в SiteTime, а поле неожиданного типа не ломает
+// разбор всего ответа - оно остается пустым (см. decodeTolerant)
+type Project struct {
+	ID                  string     `json:"id"`
+	ProjectID           string     `json:"projectId"`
+	Title               string     `json:"title"`
+	DevelopedDepartment Department `json:"developedDepartment"`
+	Stage               string     `json:"stage"`
+	Status              string     `json:"status"`
+	RegulatoryImpact    string     `json:"regulatoryImpact"`
+	Procedure           Procedure  `json:"procedure"`
+	Okveds              []Okved    `json:"okveds"`
+	KeyWords            NamedList  `json:"keyWords"`
+	Developers          NamedList  `json:"developers"`
+
+	CreationDate                      SiteTime `json:"creationDate"`
+	StartPublicDiscussion             SiteTime `json:"startPublicDiscussion"`
+	EndPublicDiscussion               SiteTime `json:"endPublicDiscussion"`
+	StartParallelPublicDiscussion     SiteTime `json:"startParallelPublicDiscussion"`
+	EndParallelPublicDiscussion       SiteTime `json:"endParallelPublicDiscussion"`
+	StartPublicNotificationDiscussion SiteTime `json:"startPublicNotificationDiscussion"`
+	EndPublicNotificationDiscussion   SiteTime `json:"endPublicNotificationDiscussion"`
+	StartPublicTextDiscussion         SiteTime `json:"startPublicTextDiscussion"`
+	EndPublicTextDiscussion           SiteTime `json:"endPublicTextDiscussion"`
+	StartPublicGradeConsultations     SiteTime `json:"startPublicGradeConsultations"`
+	EndPublicGradeConsultations       SiteTime `json:"endPublicGradeConsultations"`
+	Deadline                          SiteTime `json:"deadline"`
+	PublicationDate                   SiteTime `json:"publicationDate"`
+
+	ImportantForRegions   bool  `json:"importantForRegions"`
+	SupervisoryActivities bool  `json:"supervisoryActivities"`
+	Guillotine            bool  `json:"guillotine"`
+	Hidden                bool  `json:"hidden"`
+	IsOrv                 *bool `json:"isOrv"`
+
+	NpaStatistics NpaStatistics `json:"npaStatistics"`
+
+	// Поля, структура которых в публичном API не описана, сохраняются как есть
+	DeveloperUser        json.RawMessage `json:"developerUser,omitempty"`
+	ResponsibleEmployee  json.RawMessage `json:"responsibleEmployee,omitempty"`
+	ProjectType          json.RawMessage `json:"projectType,omitempty"`
+	Workflow             json.RawMessage `json:"workflow,omitempty"`
+	NotificationList     json.RawMessage `json:"notificationList,omitempty"`
+	ContactList          json.RawMessage `json:"contactList,omitempty"`
+	ReasonForDevelopment json.RawMessage `json:"reasonForDevelopment,omitempty"`
+	LinkedNpa            json.RawMessage `json:"linkedNpa,omitempty"`
+	NpaDiscussionStat    json.RawMessage `json:"npaDiscussionStat,omitempty"`
+	NpaRatings           json.RawMessage `json:"npaRatings,omitempty"`
+}
+
+// ProjectURL возвращает адрес страницы проекта на regulation.gov.ru
+func (p Project) ProjectURL() string {
+	return "https://regulation.gov.ru/projects/" + p.ID
+}
+
+// NumericID возвращает числовой ID проекта (0, если ID не число)
+func (p Project) NumericID() int {
+	id, _ := strconv.Atoi(p.ID)
+	return id
+}
+
+func (p *Project) UnmarshalJSON(data []byte) error {
+	type plain Project
+	return decodeTolerant(data, (*plain)(p))
+}
+
+// Department - орган власти (разработчик проекта)
+type Department struct {
+	ImageID     string `json:"imageId"`
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+func (d *Department) UnmarshalJSON(data []byte) error {
+	type plain Department
+	return decodeTolerant(data, (*plain)(d))
+}
+
+// Okved - вид экономической деятельности по ОКВЭД, который затрагивает проект
+type Okved struct {
+	ImageID     string `json:"imageId"`
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// Code возвращает код ОКВЭД: ID, если это код, иначе код в начале описания ("49.1 Деятельность ...")
+func (o Okved) Code() string {
+	if isOkvedCode(o.ID) {
+		return o.ID
+	}
+	if fields := strings.Fields(o.Description); len(fields) > 0 && isOkvedCode(fields[0]) {
+		return fields[0]
+	}
+	return o.ID
+}
+
+// isOkvedCode проверяет, что строка похожа на код ОКВЭД: цифры, разделенные точками
+func isOkvedCode(s string) bool {
+	if s == "" || s[0] == '.' || s[len(s)-1] == '.' {
+		return false
+	}
+	for _, r := range s {
+		if r != '.' && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *Okved) UnmarshalJSON(data []byte) error {
+	type plain Okved
+	return decodeTolerant(data, (*plain)(o))
+}
+
+// Procedure - процедура рассмотрения проекта
+type Procedure struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+func (p *Procedure) UnmarshalJSON(data []byte) error {
+	type plain Procedure
+	return decodeTolerant(data, (*plain)(p))
+}
+
+// NpaStatistics - статистика проекта на сайте
+type NpaStatistics struct {
+	Views       int    `json:"views"`
+	Rating      int    `json:"rating"`
+	Comments    int    `json:"comments"`
+	Description string `json:"description"`
+}
+
+func (s *NpaStatistics) UnmarshalJSON(data []byte) error {
+	type plain NpaStatistics
+	return decodeTolerant(data, (*plain)(s))
 }
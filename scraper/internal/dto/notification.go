@@ -0,0 +1,135 @@
+package dto
+
+// Notification описывает одно найденное совпадение, которое нужно доставить во все каналы
+type Notification struct {
+	ProjectID string `json:"projectId,omitempty"`
+	// ProjectNumber - полный номер проекта ("ID проекта" в RSS)
+	ProjectNumber string `json:"projectNumber,omitempty"`
+	ProjectURL    string `json:"projectUrl"`
+	FileURL       string `json:"fileUrl"`
+	FileName      string `json:"fileName,omitempty"`
+	// Stage - стадия проекта, к которой относится файл
+	Stage    string   `json:"stage,omitempty"`
+	Keywords []string `json:"keywords"`
+	Snippets []string `json:"snippets,omitempty"`
+	// Score - число вхождений ключевых слов в документе (или в карточке проекта)
+	Score       int       `json:"score,omitempty"`
+	PubDate     string    `json:"pubDate"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Department  string    `json:"department,omitempty"`
+	Procedure   string    `json:"procedure,omitempty"`
+	Kind        string    `json:"kind,omitempty"`
+	Okveds      []string  `json:"okveds,omitempty"`
+	Deadlines   Deadlines `json:"deadlines"`
+}
+
+// Deadlines - сроки публичного обсуждения проекта, если они известны
+type Deadlines struct {
+	DiscussionStart string `json:"discussionStart,omitempty"`
+	DiscussionEnd   string `json:"discussionEnd,omitempty"`
+}
+
+// IsFile сообщает, относится ли совпадение к вложению, а не к странице проекта
+func (n Notification) IsFile() bool {
+	return n.FileURL != "" && n.FileURL != n.ProjectURL
+}
+
+// SinkFilter задаёт, какие совпадения попадают в конкретный канал доставки.
+// Пустой фильтр пропускает всё.
+type SinkFilter struct {
+	// Keywords - отправлять, только если найдено хотя бы одно из этих слов
+	Keywords []string `json:"keywords,omitempty"`
+	// ExcludeKeywords - не отправлять, если найдено любое из этих слов
+	ExcludeKeywords []string `json:"excludeKeywords,omitempty"`
+	// FilesOnly - пропускать совпадения на страницах проектов
+	FilesOnly bool `json:"filesOnly,omitempty"`
+
+	// Фильтры по сведениям о проекте: значения внутри поля объединяются через ИЛИ,
+	// поля между собой и с ключевыми словами - через И
+
+	// Departments - разработчик содержит одну из строк (например, "Минфин")
+	Departments []string `json:"departments,omitempty"`
+	// Okveds - коды ОКВЭД: "49" подходит и к "49.1", и к "49.10"
+	Okveds []string `json:"okveds,omitempty"`
+	// Procedures - процедура содержит одну из строк
+	Procedures []string `json:"procedures,omitempty"`
+	// Kinds - вид акта содержит одну из строк (например, "Проект федерального закона")
+	Kinds []string `json:"kinds,omitempty"`
+}
+
+// SinkConfig описывает один канал доставки уведомлений из data/notifiers.json
+type SinkConfig struct {
+	Name    string     `json:"name"`
+	Type    string     `json:"type"` // telegram, email, webhook, slack, matrix
+	Enabled *bool      `json:"enabled,omitempty"`
+	Filter  SinkFilter `json:"filter"`
+
+	Telegram *TelegramSinkConfig `json:"telegram,omitempty"`
+	Email    *EmailSinkConfig    `json:"email,omitempty"`
+	Webhook  *WebhookSinkConfig  `json:"webhook,omitempty"`
+	Slack    *SlackSinkConfig    `json:"slack,omitempty"`
+	Matrix   *MatrixSinkConfig   `json:"matrix,omitempty"`
+}
+
+// IsEnabled возвращает true, если канал не выключен явно
+func (c SinkConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// TelegramSinkConfig - настройки Telegram; пустые поля берутся из .env
+type TelegramSinkConfig struct {
+	Token          string `json:"token,omitempty"`
+	ChatID         string `json:"chatId,omitempty"`
+	SendAsDocument *bool  `json:"sendAsDocument,omitempty"`
+}
+
+// EmailSinkConfig - настройки SMTP
+type EmailSinkConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	// TLS - режим шифрования: starttls (по умолчанию), tls (неявный TLS, обычно порт 465) или none
+	TLS                string `json:"tls,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+	// Digest - копить совпадения и отправлять одно письмо в конце сканирования
+	Digest        bool   `json:"digest,omitempty"`
+	SubjectPrefix string `json:"subjectPrefix,omitempty"`
+	// NoAttachments - не прикладывать найденные документы к письму
+	NoAttachments bool `json:"noAttachments,omitempty"`
+	// MaxAttachmentMB - максимальный суммарный размер вложений в одном письме (по умолчанию 20)
+	MaxAttachmentMB int `json:"maxAttachmentMb,omitempty"`
+}
+
+// WebhookSinkConfig - настройки произвольного JSON вебхука
+type WebhookSinkConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Secret - ключ для подписи тела запроса HMAC-SHA256
+	Secret string `json:"secret,omitempty"`
+	// SignatureHeader - заголовок с подписью (по умолчанию X-Law-Scraper-Signature)
+	SignatureHeader string `json:"signatureHeader,omitempty"`
+	// MaxAttempts - количество попыток доставки (по умолчанию 5)
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// RetryDelaySeconds - задержка перед первой повторной попыткой, далее удваивается (по умолчанию 2)
+	RetryDelaySeconds int `json:"retryDelaySeconds,omitempty"`
+	// DeadLetterFile - куда записывать недоставленные сообщения (по умолчанию data/webhook_dead_letter.jsonl)
+	DeadLetterFile string `json:"deadLetterFile,omitempty"`
+}
+
+// SlackSinkConfig - настройки входящего вебхука Slack/Mattermost
+type SlackSinkConfig struct {
+	WebhookURL string `json:"webhookUrl"`
+	Channel    string `json:"channel,omitempty"`
+	Username   string `json:"username,omitempty"`
+}
+
+// MatrixSinkConfig - настройки отправки в комнату Matrix
+type MatrixSinkConfig struct {
+	HomeserverURL string `json:"homeserverUrl"`
+	AccessToken   string `json:"accessToken"`
+	RoomID        string `json:"roomId"`
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "strings"
+
+// ProjectDetails - карточка проекта из JSON API сайта и его стадии с файлами
+type ProjectDetails struct {
+	Project
+	Stages []ProjectStage
+	// StagesKnown - стадии удалось получить
+	StagesKnown bool
+}
+
+// SearchText возвращает текст полей карточки, по которым ищутся ключевые слова на уровне проекта:
+// заголовок, разработчик, процедура, стадия, ключевые слова, разработчики и ОКВЭД
+func (p Project) SearchText() string {
+	parts := []string{
+		p.Title,
+		p.DevelopedDepartment.Description,
+		p.Procedure.Description,
+		p.Stage,
+		p.Status,
+		p.RegulatoryImpact,
+	}
+	parts = append(parts, p.KeyWords...)
+	parts = append(parts, p.Developers...)
+	for _, o := range p.Okveds {
+		parts = append(parts, o.Description)
+	}
+
+	var b strings.Builder
+	for _, s := range parts {
+		if s = strings.TrimSpace(s); s != "" {
+			b.WriteString(s)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
@@ -1,23 +1,52 @@
 package dto
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"time"
+)
 
 type RSS struct {
-    XMLName xml.Name  `xml:"rss"`
-    Channel RSSChannel `xml:"channel"`
+	XMLName xml.Name   `xml:"rss"`
+	Channel RSSChannel `xml:"channel"`
 }
 
 type RSSChannel struct {
-    Title string     `xml:"title"`
-    Link  string     `xml:"link"`
-    Items []RSSItem  `xml:"item"`
+	Title string    `xml:"title"`
+	Link  string    `xml:"link"`
+	Items []RSSItem `xml:"item"`
 }
 
 type RSSItem struct {
-    Title       string `xml:"title"`
-    Link        string `xml:"link"`
-    Description string `xml:"description"`
-    PubDate     string `xml:"pubDate"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	// Info - разобранное описание; заполняется при загрузке ленты (ParseItems)
+	Info *ProjectInfo `xml:"-" json:",omitempty"`
 }
 
+// ParseItems разбирает описания элементов ленты. Лента regulation.gov.ru не заполняет pubDate,
+// поэтому он выводится из даты создания проекта
+func (r *RSS) ParseItems() {
+	for i := range r.Channel.Items {
+		r.Channel.Items[i].ParseInfo()
+	}
+}
+
+// ParseInfo разбирает описание элемента в Info и заполняет пустой PubDate датой создания
+func (it *RSSItem) ParseInfo() {
+	info := ParseDescription(it.Description)
+	it.Info = &info
+	if it.PubDate == "" && !info.Created.IsZero() {
+		it.PubDate = info.Created.Format(time.RFC1123Z)
+	}
+}
 
+// ProjectInfo возвращает разобранное описание; элементы, сохраненные до появления Info,
+// разбираются на лету
+func (it RSSItem) ProjectInfo() ProjectInfo {
+	if it.Info != nil {
+		return *it.Info
+	}
+	return ParseDescription(it.Description)
+}
@@ -0,0 +1,229 @@
+package dto
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ответ GetProjectStages/{id} - список стадий проекта (иногда обернутый в {"result": [...]}
+// или {"stages": [...]}), у каждой стадии - название и документы:
+//
+//	[{"id": "...", "stageName": "Уведомление", "files": [
+//	    {"id": "<uuid>", "fileName": "Текст проекта.docx", "fileSize": 12345, "createDate": "2025-10-30T10:00:00"}]}]
+//
+// Названия полей в разных версиях API различаются, поэтому для каждого значения проверяется
+// несколько вариантов ключей (stageFields, fileFields)
+
+// uuidRe - формат ID файла
+var uuidRe = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[1-5][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// Варианты ключей полей стадии и файла
+var (
+	stageNameKeys  = []string{"stageName", "name", "title", "stageType", "description"}
+	stageFilesKeys = []string{"files", "documents", "attachments", "stageFiles", "fileList"}
+	fileIDKeys     = []string{"fileId", "id", "guid", "fileGuid"}
+	fileNameKeys   = []string{"fileName", "originalName", "name", "title", "description"}
+	fileSizeKeys   = []string{"fileSize", "size", "length"}
+	fileDateKeys   = []string{"createDate", "creationDate", "uploadDate", "date", "created"}
+)
+
+// ProjectStage - стадия проекта с ее файлами
+type ProjectStage struct {
+	ID    string      `json:"id,omitempty"`
+	Name  string      `json:"name"`
+	Files []StageFile `json:"files"`
+}
+
+// StageFile - файл стадии проекта
+type StageFile struct {
+	ID string `json:"id"`
+	// URL - адрес загрузки; заполняется клиентом
+	URL string `json:"url"`
+	// Name - исходное имя файла на сайте
+	Name     string    `json:"name,omitempty"`
+	Size     int64     `json:"size,omitempty"`
+	Uploaded time.Time `json:"uploaded,omitzero"`
+	// Stage - название стадии, к которой относится файл
+	Stage string `json:"stage,omitempty"`
+}
+
+// ParseProjectStages разбирает ответ GetProjectStages. Файлом считается только объект
+// с UUID в поле ID внутри списка документов стадии
+func ParseProjectStages(data []byte) ([]ProjectStage, error) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if obj, ok := raw.(map[string]any); ok {
+		for _, key := range []string{"result", "stages", "items", "data"} {
+			if v, ok := lookup(obj, key); ok {
+				raw = v
+				break
+			}
+		}
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		// Один объект стадии без списка
+		list = []any{raw}
+	}
+
+	var stages []ProjectStage
+	for _, v := range list {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		stage := ProjectStage{ID: stringField(obj, []string{"id", "stageId"}), Name: nameField(obj, stageNameKeys)}
+		for _, key := range stageFilesKeys {
+			if docs, ok := lookup(obj, key); ok {
+				stage.Files = appendStageFiles(stage.Files, docs, stage.Name)
+			}
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// appendStageFiles добавляет файлы из списка документов; документ без UUID может содержать
+// собственный список файлов (версии документа)
+func appendStageFiles(files []StageFile, docs any, stage string) []StageFile {
+	list, ok := docs.([]any)
+	if !ok {
+		return files
+	}
+	for _, v := range list {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		id := ""
+		for _, key := range fileIDKeys {
+			if s := stringField(obj, []string{key}); uuidRe.MatchString(s) {
+				id = s
+				break
+			}
+		}
+		if id == "" {
+			for _, key := range stageFilesKeys {
+				if nested, ok := lookup(obj, key); ok {
+					files = appendStageFiles(files, nested, stage)
+				}
+			}
+			continue
+		}
+		f := StageFile{ID: id, Name: stringField(obj, fileNameKeys), Stage: stage}
+		if s := stringField(obj, fileSizeKeys); s != "" {
+			f.Size, _ = strconv.ParseInt(s, 10, 64)
+		}
+		if s := stringField(obj, fileDateKeys); s != "" {
+			f.Uploaded, _ = ParseSiteDate(s)
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// StageFiles возвращает файлы всех стадий по порядку без повторов
+func StageFiles(stages []ProjectStage) []StageFile {
+	seen := map[string]bool{}
+	var files []StageFile
+	for _, st := range stages {
+		for _, f := range st.Files {
+			if seen[f.ID] {
+				continue
+			}
+			seen[f.ID] = true
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// lookup ищет ключ без учета регистра
+func lookup(obj map[string]any, key string) (any, bool) {
+	if v, ok := obj[key]; ok {
+		return v, true
+	}
+	for k, v := range obj {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// stringField возвращает первое непустое строковое или числовое значение из ключей keys
+func stringField(obj map[string]any, keys []string) string {
+	for _, key := range keys {
+		v, ok := lookup(obj, key)
+		if !ok {
+			continue
+		}
+		switch t := v.(type) {
+		case string:
+			if t = strings.TrimSpace(t); t != "" {
+				return t
+			}
+		case float64:
+			return strconv.FormatFloat(t, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// nameField - как stringField, но значение может быть и справочником {"description": "..."}
+func nameField(obj map[string]any, keys []string) string {
+	for _, key := range keys {
+		v, ok := lookup(obj, key)
+		if !ok {
+			continue
+		}
+		switch t := v.(type) {
+		case string:
+			if t = strings.TrimSpace(t); t != "" {
+				return t
+			}
+		case map[string]any:
+			if s := stringField(t, []string{"description", "name", "title"}); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// CollectFileIDs обходит произвольный JSON и собирает все строки в формате ID файла (UUID)
+func CollectFileIDs(data []byte) []string {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	var out []string
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			for _, vv := range t {
+				if s, ok := vv.(string); ok && uuidRe.MatchString(s) {
+					if _, ok := seen[s]; !ok {
+						seen[s] = struct{}{}
+						out = append(out, s)
+					}
+				}
+				walk(vv)
+			}
+		case []any:
+			for _, it := range t {
+				walk(it)
+			}
+		}
+	}
+	walk(root)
+	return out
+}
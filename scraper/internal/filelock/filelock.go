@@ -0,0 +1,10 @@
+// Package filelock - блокировка файлов данных между процессами. Бот, cron, cmd/scraper
+// и cmd/backfill работают с одним каталогом data/, поэтому перед изменением общего файла
+// процесс берет блокировку на соседний файл *.lock
+package filelock
+
+// Lock берет блокировку файла path (создавая его при необходимости): исключительную
+// для записи или разделяемую для чтения. Возвращает функцию, снимающую блокировку
+func Lock(path string, exclusive bool) (unlock func(), err error) {
+	return lock(path, exclusive)
+}
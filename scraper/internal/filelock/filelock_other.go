@@ -0,0 +1,9 @@
+//go:build !unix
+
+package filelock
+
+// На системах без flock блокировка между процессами не выполняется: согласованность
+// гарантируется только внутри одного процесса
+func lock(string, bool) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+	"github.com/notenoughtea/law_scraper/internal/service"
+)
+
+const (
+	// apiSearchDefaultLimit и apiSearchMaxLimit - число проектов в ответе /api/search
+	apiSearchDefaultLimit = 20
+	apiSearchMaxLimit     = 100
+	// apiMatchesDefaultLimit и apiMatchesMaxLimit - число записей в ответе /api/matches
+	apiMatchesDefaultLimit = 100
+	apiMatchesMaxLimit     = 1000
+)
+
+// searchResponse - ответ /api/search
+type searchResponse struct {
+	Query   string                 `json:"query"`
+	Total   int                    `json:"total"`
+	Results []service.SearchResult `json:"results"`
+}
+
+// matchesResponse - ответ /api/matches
+type matchesResponse struct {
+	Total   int                      `json:"total"`
+	Matches []repository.MatchRecord `json:"matches"`
+}
+
+// NewAPIHandler возвращает HTTP-обработчик API:
+//
+//	GET /api/search?q=запрос&limit=20 - проекты по убыванию релевантности с фрагментами текста
+//	GET /api/matches?days=7&scan=ID&files=1&limit=100 - записи журнала совпадений, новые первыми
+//	GET /api/matches/report?days=7 - сводка журнала совпадений за период
+func NewAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", handleAPISearch)
+	mux.HandleFunc("/api/matches", handleAPIMatches)
+	mux.HandleFunc("/api/matches/report", handleAPIMatchesReport)
+	return mux
+}
+
+func handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSONError(w, http.StatusBadRequest, "не указан параметр q")
+		return
+	}
+	limit := apiSearchDefaultLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "некорректный параметр limit")
+			return
+		}
+		limit = min(n, apiSearchMaxLimit)
+	}
+
+	results := service.SearchDocuments(query, limit)
+	if results == nil {
+		results = []service.SearchResult{}
+	}
+	writeJSON(w, http.StatusOK, searchResponse{Query: query, Total: len(results), Results: results})
+}
+
+func handleAPIMatches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+		return
+	}
+	q := repository.MatchQuery{ScanID: r.URL.Query().Get("scan"), FilesOnly: r.URL.Query().Get("files") == "1"}
+	if s := r.URL.Query().Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "некорректный параметр days")
+			return
+		}
+		q.Since = time.Now().AddDate(0, 0, -n)
+	}
+	limit := apiMatchesDefaultLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "некорректный параметр limit")
+			return
+		}
+		limit = min(n, apiMatchesMaxLimit)
+	}
+
+	records, err := repository.LoadMatches(q)
+	if err != nil {
+		logger.Log.Errorf("Ошибка чтения журнала совпадений: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "ошибка чтения журнала совпадений")
+		return
+	}
+	total := len(records)
+	slices.Reverse(records)
+	records = records[:min(limit, total)]
+	writeJSON(w, http.StatusOK, matchesResponse{Total: total, Matches: records})
+}
+
+func handleAPIMatchesReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "метод не поддерживается")
+		return
+	}
+	days := matchesDefaultDays
+	if s := r.URL.Query().Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "некорректный параметр days")
+			return
+		}
+		days = n
+	}
+	report, err := service.BuildMatchReport(time.Now().AddDate(0, 0, -days), 10)
+	if err != nil {
+		logger.Log.Errorf("Ошибка чтения журнала совпадений: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "ошибка чтения журнала совпадений")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Log.Warnf("Ошибка записи ответа API: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// StartAPIServer запускает HTTP API на адресе API_ADDR (если он задан) и останавливает его
+// при отмене ctx
+func StartAPIServer(ctx context.Context) {
+	addr := config.GetAPIAddr()
+	if addr == "" {
+		return
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           NewAPIHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		logger.Log.Infof("🌐 HTTP API запущено на %s", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Errorf("❌ Ошибка HTTP API: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Log.Warnf("Ошибка остановки HTTP API: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/notenoughtea/law_scraper/internal/clients"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/service"
+)
+
+const (
+	// matchesDefaultDays - период сводки /matches по умолчанию
+	matchesDefaultDays = 7
+	// matchesTopKeywords и matchesTopFiles - сколько слов и файлов показывать в сводке
+	matchesTopKeywords = 10
+	matchesTopFiles    = 5
+)
+
+// handleMatches обрабатывает команду /matches [дни] - сводка журнала совпадений
+func (h *TelegramBotHandler) handleMatches(msg *tgbotapi.Message) {
+	days := matchesDefaultDays
+	if arg := strings.TrimSpace(msg.CommandArguments()); arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			h.sendMessage(msg.Chat.ID, "❌ Укажите период в днях.\n\nПример:\n/matches 30")
+			return
+		}
+		days = n
+	}
+
+	report, err := service.BuildMatchReport(time.Now().AddDate(0, 0, -days), matchesTopFiles)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ <b>Ошибка чтения журнала совпадений:</b>\n\n%s", clients.EscapeHTML(err.Error())))
+		logger.Log.Errorf("Ошибка чтения журнала совпадений: %v", err)
+		return
+	}
+	if report.Matches == 0 {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("📊 За последние %d дн. совпадений не было", days))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📊 <b>Совпадения за последние %d дн.</b>\n\n", days)
+	fmt.Fprintf(&b, "Сканирований: %d\nПроектов: %d\nСовпадений: %d (во вложениях %d)\n",
+		report.Scans, report.Projects, report.Matches, report.Files)
+	if report.Undelivered > 0 {
+		fmt.Fprintf(&b, "Без доставленного уведомления: %d\n", report.Undelivered)
+	}
+
+	b.WriteString("\n🔑 <b>Ключевые слова:</b>\n")
+	for i, kw := range report.Keywords {
+		if i == matchesTopKeywords {
+			fmt.Fprintf(&b, "… и еще %d\n", len(report.Keywords)-matchesTopKeywords)
+			break
+		}
+		fmt.Fprintf(&b, "• %s - %d\n", clients.EscapeHTML(kw.Keyword), kw.Matches)
+	}
+
+	if len(report.Top) > 0 {
+		b.WriteString("\n📄 <b>Больше всего вхождений:</b>\n")
+		for i, rec := range report.Top {
+			name := rec.FileName
+			if name == "" {
+				name = rec.FileURL
+			}
+			fmt.Fprintf(&b, "%d. <a href=\"%s\">%s</a> (%d)\n", i+1,
+				clients.EscapeHTML(rec.FileURL), clients.EscapeHTML(name), rec.Score)
+		}
+	}
+	h.sendMessage(msg.Chat.ID, b.String())
+}
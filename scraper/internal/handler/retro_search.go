@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/notenoughtea/law_scraper/internal/clients"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/service"
+)
+
+const (
+	// retroCallbackPrefix - префикс данных кнопки поиска по скачанным документам: retro:<дни>:<слово>
+	retroCallbackPrefix = "retro:"
+	// retroDefaultDays - период поиска по умолчанию
+	retroDefaultDays = 30
+	// retroMaxResults - сколько проектов показывать в ответе
+	retroMaxResults = 20
+)
+
+// retroDays - варианты периода на кнопках
+var retroDays = []int{7, 30, 90}
+
+// offerRetroSearch предлагает поискать слово в уже скачанных документах кнопками с периодом
+func (h *TelegramBotHandler) offerRetroSearch(chatID int64, keyword string) {
+	text := fmt.Sprintf("🔎 Поискать '%s' в уже скачанных документах?", clients.EscapeHTML(keyword))
+
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, days := range retroDays {
+		data := fmt.Sprintf("%s%d:%s", retroCallbackPrefix, days, keyword)
+		// Данные кнопки ограничены 64 байтами - для длинных фраз остается команда
+		if len(data) > 64 {
+			buttons = nil
+			break
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("за %d дн.", days), data))
+	}
+	if len(buttons) == 0 {
+		h.sendMessage(chatID, text+fmt.Sprintf("\n\nОтправьте: /retro_search %s %d", clients.EscapeHTML(keyword), retroDefaultDays))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(buttons...))
+	if _, err := h.bot.Send(msg); err != nil {
+		logger.Log.Errorf("Ошибка отправки сообщения: %v", err)
+	}
+}
+
+// handleCallback обрабатывает нажатия на inline-кнопки
+func (h *TelegramBotHandler) handleCallback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil || !strings.HasPrefix(cq.Data, retroCallbackPrefix) {
+		return
+	}
+	daysStr, keyword, ok := strings.Cut(strings.TrimPrefix(cq.Data, retroCallbackPrefix), ":")
+	days, err := strconv.Atoi(daysStr)
+	if !ok || err != nil || days <= 0 || keyword == "" {
+		return
+	}
+	if _, err := h.bot.Request(tgbotapi.NewCallback(cq.ID, "🔎 Ищу...")); err != nil {
+		logger.Log.Warnf("Не удалось ответить на нажатие кнопки: %v", err)
+	}
+	go h.runRetroSearch(cq.Message.Chat.ID, keyword, days)
+}
+
+// handleRetroSearch обрабатывает команду /retro_search слово [дни]
+func (h *TelegramBotHandler) handleRetroSearch(msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	days := retroDefaultDays
+	if len(args) > 1 {
+		if n, err := strconv.Atoi(args[len(args)-1]); err == nil && n > 0 {
+			days = n
+			args = args[:len(args)-1]
+		}
+	}
+	keyword := strings.Join(args, " ")
+	if keyword == "" {
+		h.sendMessage(msg.Chat.ID, "❌ Укажите слово для поиска.\n\nПример:\n/retro_search экология 90")
+		return
+	}
+	go h.runRetroSearch(msg.Chat.ID, keyword, days)
+}
+
+// runRetroSearch ищет слово в сохраненных текстах и отправляет список проектов
+func (h *TelegramBotHandler) runRetroSearch(chatID int64, keyword string, days int) {
+	keyword = strings.ToLower(keyword)
+	matches, err := service.SearchStoredTexts(context.Background(), keyword, days)
+	if err != nil {
+		h.sendMessage(chatID, fmt.Sprintf("❌ <b>Ошибка поиска:</b>\n\n%s", clients.EscapeHTML(err.Error())))
+		logger.Log.Errorf("Ошибка поиска по сохраненным документам: %v", err)
+		return
+	}
+	if len(matches) == 0 {
+		h.sendMessage(chatID, fmt.Sprintf("🔎 '%s' не найдено в документах, скачанных за последние %d дн.",
+			clients.EscapeHTML(keyword), days))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔎 <b>'%s' за последние %d дн.: проектов %d</b>\n",
+		clients.EscapeHTML(keyword), days, len(matches))
+	for i, m := range matches {
+		if i == retroMaxResults {
+			fmt.Fprintf(&b, "\n… и еще %d", len(matches)-retroMaxResults)
+			break
+		}
+		title := m.Title
+		if title == "" {
+			title = m.ProjectURL
+		}
+		fmt.Fprintf(&b, "\n%d. <a href=\"%s\">%s</a>\n📄 %s\n", i+1,
+			clients.EscapeHTML(m.ProjectURL), clients.EscapeHTML(title), clients.EscapeHTML(strings.Join(m.Files, ", ")))
+		if m.Snippet != "" {
+			fmt.Fprintf(&b, "<i>%s</i>\n", clients.EscapeHTML(m.Snippet))
+		}
+	}
+	h.sendMessage(chatID, b.String())
+	logger.Log.Infof("Поиск '%s' по сохраненным документам за %d дн.: проектов %d", keyword, days, len(matches))
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/notenoughtea/law_scraper/internal/clients"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/service"
+)
+
+// searchMaxResults - сколько проектов показывать в ответе на /search
+const searchMaxResults = 10
+
+// handleSearch обрабатывает команду /search запрос - поиск по индексу всех проверенных документов
+func (h *TelegramBotHandler) handleSearch(msg *tgbotapi.Message) {
+	query := strings.TrimSpace(msg.CommandArguments())
+	if query == "" {
+		h.sendMessage(msg.Chat.ID, "❌ Укажите запрос.\n\nПример:\n/search государственная экологическая экспертиза")
+		return
+	}
+	go h.runSearch(msg.Chat.ID, query)
+}
+
+// runSearch выполняет поиск и отправляет проекты по убыванию релевантности
+func (h *TelegramBotHandler) runSearch(chatID int64, query string) {
+	results := service.SearchDocuments(query, searchMaxResults)
+	if len(results) == 0 {
+		h.sendMessage(chatID, fmt.Sprintf("🔎 По запросу '%s' ничего не найдено", clients.EscapeHTML(query)))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔎 <b>'%s': проектов %d</b>\n", clients.EscapeHTML(query), len(results))
+	for i, r := range results {
+		title := r.Title
+		if title == "" {
+			title = r.ProjectURL
+		}
+		fmt.Fprintf(&b, "\n%d. <a href=\"%s\">%s</a>\n", i+1, clients.EscapeHTML(r.ProjectURL), clients.EscapeHTML(title))
+		names := make([]string, 0, len(r.Files))
+		for _, f := range r.Files {
+			name := f.FileName
+			if name == "" {
+				name = f.FileURL
+			}
+			names = append(names, name)
+		}
+		fmt.Fprintf(&b, "📄 %s\n", clients.EscapeHTML(strings.Join(names, ", ")))
+		if s := r.Files[0].Snippet; s != "" {
+			fmt.Fprintf(&b, "<i>%s</i>\n", clients.EscapeHTML(s))
+		}
+	}
+	h.sendMessage(chatID, b.String())
+	logger.Log.Infof("Поиск '%s' по индексу документов: проектов %d", query, len(results))
+}
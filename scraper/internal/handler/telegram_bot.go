@@ -1,32 +1,35 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
-	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/notenoughtea/law_scraper/internal/clients"
 	"github.com/notenoughtea/law_scraper/internal/logger"
 	"github.com/notenoughtea/law_scraper/internal/repository"
 	"github.com/notenoughtea/law_scraper/internal/service"
 )
 
 type TelegramBotHandler struct {
-	bot          *tgbotapi.BotAPI
-	scanMutex    sync.Mutex
-	isScanning   bool
+	bot *tgbotapi.BotAPI
 }
 
 // NewTelegramBotHandler создает новый обработчик команд Telegram бота
 func NewTelegramBotHandler(bot *tgbotapi.BotAPI) *TelegramBotHandler {
 	return &TelegramBotHandler{
-		bot:        bot,
-		isScanning: false,
+		bot: bot,
 	}
 }
 
 // HandleUpdate обрабатывает входящее обновление от Telegram
 func (h *TelegramBotHandler) HandleUpdate(update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		h.handleCallback(update.CallbackQuery)
+		return
+	}
 	if update.Message == nil {
 		return
 	}
@@ -67,6 +70,14 @@ func (h *TelegramBotHandler) handleCommand(msg *tgbotapi.Message) {
 		h.handleRemoveKeyword(msg)
 	case "scan":
 		h.handleScan(msg)
+	case "cancel_scan":
+		h.handleCancelScan(msg)
+	case "retro_search":
+		h.handleRetroSearch(msg)
+	case "search":
+		h.handleSearch(msg)
+	case "matches":
+		h.handleMatches(msg)
 	case "clear_data":
 		h.handleClearData(msg)
 	default:
@@ -108,9 +119,24 @@ func (h *TelegramBotHandler) sendHelp(chatID int64) {
    Удалить ключевое слово
    Пример: /remove_keyword транспорт
 
+<b>/retro_search</b> слово [дни]
+   Найти слово в уже скачанных документах за последние дни (по умолчанию 30)
+   Пример: /retro_search экология 90
+
+<b>/search</b> запрос
+   Полнотекстовый поиск по всем проверенным документам (с учетом словоформ)
+   Пример: /search экологическая экспертиза
+
+<b>/matches</b> [дни]
+   Сводка найденных совпадений за последние дни (по умолчанию 7)
+   Пример: /matches 30
+
 <b>/scan</b> - запустить парсер вручную
    Начинает сканирование RSS и поиск по ключевым словам
 
+<b>/cancel_scan</b> - остановить текущее сканирование
+   Файлы в работе дообрабатываются, остальные проекты будут проверены в следующий раз
+
 <b>/clear_data</b> - удалить сохраненные данные
    Удаляет rss.json и pages.json (после этого все элементы будут считаться новыми)
 
@@ -132,7 +158,7 @@ func (h *TelegramBotHandler) handleKeywords(msg *tgbotapi.Message) {
 	if len(keywords) == 0 {
 		response = "❌ Ключевые слова не настроены.\n\nИспользуйте /set_keywords для установки."
 	} else {
-		keywordsList := strings.Join(keywords, ", ")
+		keywordsList := clients.EscapeHTML(strings.Join(keywords, ", "))
 		response = fmt.Sprintf("🔑 <b>Текущие ключевые слова (%d):</b>\n\n%s", len(keywords), keywordsList)
 	}
 
@@ -167,11 +193,11 @@ func (h *TelegramBotHandler) handleSetKeywords(msg *tgbotapi.Message) {
 	// Сохраняем новые ключевые слова
 	if err := repository.SetKeywords(keywords); err != nil {
 		logger.Log.Errorf("Ошибка сохранения ключевых слов: %v", err)
-		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ Ошибка сохранения: %v", err))
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ Ошибка сохранения: %s", clients.EscapeHTML(err.Error())))
 		return
 	}
 
-	keywordsList := strings.Join(keywords, ", ")
+	keywordsList := clients.EscapeHTML(strings.Join(keywords, ", "))
 	response := fmt.Sprintf("✅ <b>Ключевые слова обновлены (%d):</b>\n\n%s", len(keywords), keywordsList)
 	h.sendMessage(msg.Chat.ID, response)
 	
@@ -195,17 +221,20 @@ func (h *TelegramBotHandler) handleAddKeyword(msg *tgbotapi.Message) {
 
 	if err := repository.AddKeyword(keyword); err != nil {
 		logger.Log.Errorf("Ошибка добавления ключевого слова: %v", err)
-		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ Ошибка: %v", err))
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ Ошибка: %s", clients.EscapeHTML(err.Error())))
 		return
 	}
 
 	// Показываем обновленный список
 	keywords := repository.GetCurrentKeywords()
-	keywordsList := strings.Join(keywords, ", ")
+	keywordsList := clients.EscapeHTML(strings.Join(keywords, ", "))
 	
 	response := fmt.Sprintf("✅ <b>Слово '%s' добавлено!</b>\n\n🔑 Текущие ключевые слова (%d):\n%s", 
-		strings.ToLower(keyword), len(keywords), keywordsList)
+		clients.EscapeHTML(strings.ToLower(keyword)), len(keywords), keywordsList)
 	h.sendMessage(msg.Chat.ID, response)
+
+	// Новое слово проверяется только в новых проектах - предлагаем поискать его в уже скачанных документах
+	h.offerRetroSearch(msg.Chat.ID, strings.ToLower(keyword))
 	
 	logger.Log.Infof("Пользователь %s добавил ключевое слово: %s", msg.From.UserName, keyword)
 }
@@ -221,7 +250,7 @@ func (h *TelegramBotHandler) handleRemoveKeyword(msg *tgbotapi.Message) {
 
 	if err := repository.RemoveKeyword(keyword); err != nil {
 		logger.Log.Errorf("Ошибка удаления ключевого слова: %v", err)
-		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ Ошибка: %v", err))
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ Ошибка: %s", clients.EscapeHTML(err.Error())))
 		return
 	}
 
@@ -231,11 +260,11 @@ func (h *TelegramBotHandler) handleRemoveKeyword(msg *tgbotapi.Message) {
 	var response string
 	if len(keywords) == 0 {
 		response = fmt.Sprintf("✅ <b>Слово '%s' удалено!</b>\n\n⚠️ Список ключевых слов теперь пуст.", 
-			strings.ToLower(keyword))
+			clients.EscapeHTML(strings.ToLower(keyword)))
 	} else {
-		keywordsList := strings.Join(keywords, ", ")
+		keywordsList := clients.EscapeHTML(strings.Join(keywords, ", "))
 		response = fmt.Sprintf("✅ <b>Слово '%s' удалено!</b>\n\n🔑 Текущие ключевые слова (%d):\n%s", 
-			strings.ToLower(keyword), len(keywords), keywordsList)
+			clients.EscapeHTML(strings.ToLower(keyword)), len(keywords), keywordsList)
 	}
 	
 	h.sendMessage(msg.Chat.ID, response)
@@ -245,28 +274,30 @@ func (h *TelegramBotHandler) handleRemoveKeyword(msg *tgbotapi.Message) {
 
 // handleScan обрабатывает команду /scan - запуск парсера вручную
 func (h *TelegramBotHandler) handleScan(msg *tgbotapi.Message) {
-	h.scanMutex.Lock()
-	if h.isScanning {
-		h.scanMutex.Unlock()
-		h.sendMessage(msg.Chat.ID, "⏳ Сканирование уже выполняется, пожалуйста подождите...")
+	if service.IsScanRunning() {
+		h.sendMessage(msg.Chat.ID, "⏳ Сканирование уже выполняется, пожалуйста подождите...\n\nОстановить его можно командой /cancel_scan")
 		return
 	}
-	h.isScanning = true
-	h.scanMutex.Unlock()
 
 	// Запускаем сканирование в отдельной горутине
 	go func() {
-		defer func() {
-			h.scanMutex.Lock()
-			h.isScanning = false
-			h.scanMutex.Unlock()
-		}()
-
 		h.sendMessage(msg.Chat.ID, "🚀 Запуск сканирования (параллельный режим)...\n\n⏳ Это может занять несколько минут.\n\n💡 <i>Обрабатывается параллельно с ограниченным количеством воркеров для экономии ресурсов.</i>")
-		
-		matches, err := service.RunManualScan()
-		if err != nil {
-			h.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ <b>Ошибка сканирования:</b>\n\n%v", err))
+
+		matches, err := service.RunManualScan(context.Background())
+		switch {
+		case errors.Is(err, service.ErrScanInProgress):
+			h.sendMessage(msg.Chat.ID, "⏳ Сканирование уже выполняется, пожалуйста подождите...")
+			return
+		case errors.Is(err, context.Canceled):
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("⏹ <b>Сканирование остановлено</b>\n\n📊 Найдено совпадений до остановки: %d\n\nНеобработанные проекты будут проверены при следующем сканировании.", matches))
+			logger.Log.Infof("Ручное сканирование остановлено, найдено совпадений: %d", matches)
+			return
+		case errors.Is(err, context.DeadlineExceeded):
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("⌛ <b>Сканирование прервано по таймауту</b>\n\n📊 Найдено совпадений: %d\n\nНеобработанные проекты будут проверены при следующем сканировании.", matches))
+			logger.Log.Warnf("Ручное сканирование прервано по таймауту, найдено совпадений: %d", matches)
+			return
+		case err != nil:
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ <b>Ошибка сканирования:</b>\n\n%s", clients.EscapeHTML(err.Error())))
 			logger.Log.Errorf("Ошибка ручного сканирования: %v", err)
 			return
 		}
@@ -276,6 +307,17 @@ func (h *TelegramBotHandler) handleScan(msg *tgbotapi.Message) {
 	}()
 }
 
+// handleCancelScan обрабатывает команду /cancel_scan - остановка текущего сканирования
+func (h *TelegramBotHandler) handleCancelScan(msg *tgbotapi.Message) {
+	if !service.CancelScan() {
+		h.sendMessage(msg.Chat.ID, "ℹ️ Сканирование сейчас не выполняется.")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, "⏹ Останавливаю сканирование: файлы, которые уже в работе, будут дообработаны, после чего придет итог.")
+	logger.Log.Infof("Пользователь %s остановил сканирование", msg.From.UserName)
+}
+
 // handleClearData обрабатывает команду /clear_data - удаление сохраненных данных
 func (h *TelegramBotHandler) handleClearData(msg *tgbotapi.Message) {
 	// Подтверждение перед удалением
@@ -288,14 +330,17 @@ func (h *TelegramBotHandler) handleClearData(msg *tgbotapi.Message) {
 	// Удаляем данные
 	rssErr := repository.ClearRSSData()
 	pagesErr := repository.ClearPagesData()
+	if err := repository.ClearCheckpoint(); err != nil {
+		logger.Log.Warnf("Не удалось удалить контрольную точку сканирования: %v", err)
+	}
 
 	var response string
 	if rssErr != nil && pagesErr != nil {
-		response = fmt.Sprintf("❌ <b>Ошибки при удалении:</b>\n\nRSS: %v\nPages: %v", rssErr, pagesErr)
+		response = fmt.Sprintf("❌ <b>Ошибки при удалении:</b>\n\nRSS: %s\nPages: %s", clients.EscapeHTML(rssErr.Error()), clients.EscapeHTML(pagesErr.Error()))
 	} else if rssErr != nil {
-		response = fmt.Sprintf("⚠️ <b>Частично удалено:</b>\n\n✅ pages.json удален\n❌ rss.json: %v", rssErr)
+		response = fmt.Sprintf("⚠️ <b>Частично удалено:</b>\n\n✅ pages.json удален\n❌ rss.json: %s", clients.EscapeHTML(rssErr.Error()))
 	} else if pagesErr != nil {
-		response = fmt.Sprintf("⚠️ <b>Частично удалено:</b>\n\n✅ rss.json удален\n❌ pages.json: %v", pagesErr)
+		response = fmt.Sprintf("⚠️ <b>Частично удалено:</b>\n\n✅ rss.json удален\n❌ pages.json: %s", clients.EscapeHTML(pagesErr.Error()))
 	} else {
 		response = "✅ <b>Данные успешно удалены!</b>\n\n• rss.json\n• pages.json\n\nПри следующем сканировании все элементы будут считаться новыми."
 	}
@@ -306,7 +351,8 @@ func (h *TelegramBotHandler) handleClearData(msg *tgbotapi.Message) {
 
 // sendMessage отправляет сообщение в Telegram
 func (h *TelegramBotHandler) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
+	// Страховка от ошибок разбора разметки и превышения лимита длины
+	msg := tgbotapi.NewMessage(chatID, clients.FitTelegramHTML(text, 4096))
 	msg.ParseMode = "HTML"
 	
 	if _, err := h.bot.Send(msg); err != nil {
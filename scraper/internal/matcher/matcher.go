@@ -0,0 +1,162 @@
+// Package matcher ищет сразу все ключевые слова за один проход по тексту
+// с помощью автомата Ахо–Корасик
+package matcher
+
+// node - состояние автомата
+type node struct {
+	next map[byte]int32
+	fail int32
+	// out - индексы ключевых слов, которые заканчиваются в этом состоянии (с учетом суффиксов)
+	out []int
+}
+
+// Matcher - автомат, построенный по списку ключевых слов. Ключевые слова сравниваются
+// побайтно, поэтому и они, и текст должны быть в одном регистре (в сканере - в нижнем)
+type Matcher struct {
+	keywords []string
+	nodes    []node
+}
+
+// Hit - вхождение ключевого слова: индекс слова и смещения начала и конца в байтах
+type Hit struct {
+	Keyword    int
+	Start, End int64
+}
+
+// New строит автомат по ключевым словам; пустые слова пропускаются
+func New(keywords []string) *Matcher {
+	m := &Matcher{keywords: keywords, nodes: []node{{}}}
+
+	for i, kw := range keywords {
+		if kw == "" {
+			continue
+		}
+		var cur int32
+		for j := 0; j < len(kw); j++ {
+			nxt, ok := m.nodes[cur].next[kw[j]]
+			if !ok {
+				if m.nodes[cur].next == nil {
+					m.nodes[cur].next = map[byte]int32{}
+				}
+				m.nodes = append(m.nodes, node{})
+				nxt = int32(len(m.nodes) - 1)
+				m.nodes[cur].next[kw[j]] = nxt
+			}
+			cur = nxt
+		}
+		m.nodes[cur].out = append(m.nodes[cur].out, i)
+	}
+
+	// Суффиксные ссылки строим обходом в ширину
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for b, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for {
+				if nxt, ok := m.nodes[f].next[b]; ok {
+					m.nodes[child].fail = nxt
+					break
+				}
+				if f == 0 {
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			m.nodes[child].out = append(m.nodes[child].out, m.nodes[m.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// Keywords возвращает ключевые слова, по которым построен автомат
+func (m *Matcher) Keywords() []string {
+	return m.keywords
+}
+
+// Empty сообщает, что искать нечего
+func (m *Matcher) Empty() bool {
+	return len(m.nodes) == 1
+}
+
+// step - переход автомата по байту
+func (m *Matcher) step(state int32, b byte) int32 {
+	for {
+		if nxt, ok := m.nodes[state].next[b]; ok {
+			return nxt
+		}
+		if state == 0 {
+			return 0
+		}
+		state = m.nodes[state].fail
+	}
+}
+
+// Stream - поиск по тексту, который поступает частями: состояние автомата сохраняется
+// между вызовами Write, поэтому слово, разрезанное на границе частей, тоже находится
+type Stream struct {
+	m     *Matcher
+	state int32
+	pos   int64
+	onHit func(Hit)
+}
+
+// NewStream создает потоковый поиск; onHit вызывается для каждого вхождения
+// в момент, когда прочитан его последний байт
+func (m *Matcher) NewStream(onHit func(Hit)) *Stream {
+	return &Stream{m: m, onHit: onHit}
+}
+
+// Write передает автомату очередную часть текста
+func (s *Stream) Write(p []byte) (int, error) {
+	for i, b := range p {
+		s.state = s.m.step(s.state, b)
+		if out := s.m.nodes[s.state].out; len(out) > 0 {
+			end := s.pos + int64(i) + 1
+			for _, kw := range out {
+				s.onHit(Hit{Keyword: kw, Start: end - int64(len(s.m.keywords[kw])), End: end})
+			}
+		}
+	}
+	s.pos += int64(len(p))
+	return len(p), nil
+}
+
+// Offset возвращает число байт, прочитанных потоком
+func (s *Stream) Offset() int64 {
+	return s.pos
+}
+
+// FindAll возвращает все вхождения ключевых слов в text (в том числе перекрывающиеся)
+// в порядке их окончания
+func (m *Matcher) FindAll(text []byte) []Hit {
+	var hits []Hit
+	m.NewStream(func(h Hit) { hits = append(hits, h) }).Write(text)
+	return hits
+}
+
+// Found возвращает ключевые слова, у которых есть вхождения, в порядке списка ключевых слов
+func (m *Matcher) Found(hits []Hit) []string {
+	counts := m.Count(hits)
+	var found []string
+	for i, n := range counts {
+		if n > 0 {
+			found = append(found, m.keywords[i])
+		}
+	}
+	return found
+}
+
+// Count возвращает число вхождений каждого ключевого слова (по индексу в списке)
+func (m *Matcher) Count(hits []Hit) []int {
+	counts := make([]int, len(m.keywords))
+	for _, h := range hits {
+		counts[h.Keyword]++
+	}
+	return counts
+}
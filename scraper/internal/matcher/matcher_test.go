@@ -0,0 +1,132 @@
+package matcher
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// hitString записывает вхождение как слово[начало:конец] для наглядных сравнений
+func hitString(m *Matcher, h Hit) string {
+	return fmt.Sprintf("%s[%d:%d]", m.Keywords()[h.Keyword], h.Start, h.End)
+}
+
+func hitStrings(m *Matcher, hits []Hit) []string {
+	out := make([]string, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, hitString(m, h))
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestFindAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		keywords []string
+		text     string
+		want     []string
+	}{
+		{
+			name:     "перекрывающиеся слова",
+			keywords: []string{"he", "she", "his", "hers"},
+			text:     "ushers",
+			want:     []string{"he[2:4]", "hers[2:6]", "she[1:4]"},
+		},
+		{
+			name:     "слово внутри слова",
+			keywords: []string{"тариф", "тарифы", "риф"},
+			text:     "тарифы",
+			want:     []string{"риф[4:10]", "тариф[0:10]", "тарифы[0:12]"},
+		},
+		{
+			name:     "повторы с наложением",
+			keywords: []string{"aa"},
+			text:     "aaaa",
+			want:     []string{"aa[0:2]", "aa[1:3]", "aa[2:4]"},
+		},
+		{
+			name:     "переход по суффиксной ссылке после несовпадения",
+			keywords: []string{"abcd", "bce"},
+			text:     "abce",
+			want:     []string{"bce[1:4]"},
+		},
+		{
+			name:     "одинаковые слова",
+			keywords: []string{"налог", "налог"},
+			text:     "налог",
+			want:     []string{"налог[0:10]", "налог[0:10]"},
+		},
+		{
+			name:     "пустое слово пропускается",
+			keywords: []string{"", "дом"},
+			text:     "дом",
+			want:     []string{"дом[0:6]"},
+		},
+		{
+			name:     "нет вхождений",
+			keywords: []string{"закон"},
+			text:     "постановление",
+			want:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(tt.keywords)
+			got := hitStrings(m, m.FindAll([]byte(tt.text)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FindAll(%q) = %v, ожидалось %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamFindsWordsAcrossChunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		keywords []string
+		text     string
+	}{
+		{"латиница", []string{"he", "she", "his", "hers"}, "ushers and his hershey"},
+		{"кириллица", []string{"тариф", "риф", "перевозк"}, "тарифы на перевозку грузов: тариф"},
+		{"наложения", []string{"aa", "aaa"}, "aaaaaa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(tt.keywords)
+			text := []byte(tt.text)
+			want := hitStrings(m, m.FindAll(text))
+			// Режем текст во всех точках, в том числе посреди многобайтных символов
+			for cut := 0; cut <= len(text); cut++ {
+				var hits []Hit
+				s := m.NewStream(func(h Hit) { hits = append(hits, h) })
+				s.Write(text[:cut])
+				s.Write(text[cut:])
+				if got := hitStrings(m, hits); !slices.Equal(got, want) {
+					t.Errorf("разрез на %d: %v, ожидалось %v", cut, got, want)
+				}
+				if s.Offset() != int64(len(text)) {
+					t.Errorf("разрез на %d: Offset = %d, ожидалось %d", cut, s.Offset(), len(text))
+				}
+			}
+		})
+	}
+}
+
+func TestFoundAndCount(t *testing.T) {
+	m := New([]string{"закон", "налог", "тариф"})
+	hits := m.FindAll([]byte("налог и закон о налоге"))
+
+	if got, want := m.Count(hits), []int{1, 2, 0}; !slices.Equal(got, want) {
+		t.Errorf("Count = %v, ожидалось %v", got, want)
+	}
+	if got, want := m.Found(hits), []string{"закон", "налог"}; !slices.Equal(got, want) {
+		t.Errorf("Found = %v, ожидалось %v", got, want)
+	}
+	if m.Empty() {
+		t.Error("Empty = true для непустого списка слов")
+	}
+	if !New([]string{""}).Empty() {
+		t.Error("Empty = false для списка из пустого слова")
+	}
+}
@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/filelock"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// Кэш вложений общий для бота, cron и cmd/backfill: индекс и файлы меняются только под блокировкой
+// index.lock, а индекс перечитывается, когда его заменил другой процесс
+
+// CachedAttachment - запись кэша вложений. Содержимое хранится по SHA-256,
+// поэтому одинаковые файлы с разными ID занимают место один раз
+type CachedAttachment struct {
+	FileID      string    `json:"fileId"`
+	SHA256      string    `json:"sha256"`
+	Size        int64     `json:"size"`
+	FileName    string    `json:"fileName,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+	StoredAt    time.Time `json:"storedAt"`
+	LastAccess  time.Time `json:"lastAccess"`
+}
+
+var (
+	attachmentsMutex sync.Mutex
+	attachmentsIndex map[string]*CachedAttachment
+	// attachmentsIndexFile - прочитанный файл индекса
+	attachmentsIndexFile os.FileInfo
+	// attachmentsAccess - время обращений к вложениям, еще не записанное в индекс:
+	// чтение не переписывает индекс, время попадает в него при следующем сохранении
+	attachmentsAccess = map[string]time.Time{}
+)
+
+func attachmentIndexPath() string {
+	return filepath.Join(config.GetAttachmentCacheDir(), "index.json")
+}
+
+func attachmentLockPath() string {
+	return filepath.Join(config.GetAttachmentCacheDir(), "index.lock")
+}
+
+func attachmentBlobPath(sum string) string {
+	return filepath.Join(config.GetAttachmentCacheDir(), "blobs", sum[:2], sum)
+}
+
+// loadAttachmentIndex загружает индекс кэша и перечитывает его, если файл заменен другим процессом;
+// вызывается под attachmentsMutex
+func loadAttachmentIndex() map[string]*CachedAttachment {
+	st, _ := os.Stat(attachmentIndexPath())
+	if attachmentsIndex != nil && sameFileVersion(st, attachmentsIndexFile) {
+		return attachmentsIndex
+	}
+	attachmentsIndex = map[string]*CachedAttachment{}
+	attachmentsIndexFile = st
+
+	data, err := os.ReadFile(attachmentIndexPath())
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Log.Warnf("Не удалось прочитать индекс кэша вложений: %v", err)
+		}
+		return attachmentsIndex
+	}
+	var entries []*CachedAttachment
+	if err := json.Unmarshal(data, &entries); err != nil {
+		logger.Log.Warnf("Индекс кэша вложений поврежден, начинаем заново: %v", err)
+		return attachmentsIndex
+	}
+	for _, e := range entries {
+		attachmentsIndex[e.FileID] = e
+	}
+	return attachmentsIndex
+}
+
+// lockAttachmentIndex берет блокировку кэша между процессами и загружает актуальный индекс
+// с накопленными временами обращений; вызывается под attachmentsMutex
+func lockAttachmentIndex() (map[string]*CachedAttachment, func(), error) {
+	if err := os.MkdirAll(config.GetAttachmentCacheDir(), 0o755); err != nil {
+		return nil, nil, err
+	}
+	unlock, err := filelock.Lock(attachmentLockPath(), true)
+	if err != nil {
+		return nil, nil, err
+	}
+	index := loadAttachmentIndex()
+	for fileID, at := range attachmentsAccess {
+		if e, ok := index[fileID]; ok && at.After(e.LastAccess) {
+			e.LastAccess = at
+		}
+	}
+	return index, unlock, nil
+}
+
+// saveAttachmentIndex атомарно записывает индекс; вызывается под attachmentsMutex и блокировкой index.lock
+func saveAttachmentIndex() error {
+	entries := make([]*CachedAttachment, 0, len(attachmentsIndex))
+	for _, e := range attachmentsIndex {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].FileID < entries[j].FileID })
+	if err := writeJSONAtomic(attachmentIndexPath(), entries); err != nil {
+		return err
+	}
+	attachmentsIndexFile, _ = os.Stat(attachmentIndexPath())
+	clear(attachmentsAccess)
+	return nil
+}
+
+// writeJSONAtomic записывает JSON во временный файл и переименовывает его,
+// чтобы при падении процесса не остался наполовину записанный файл.
+// Имя временного файла уникально, поэтому процессы не пишут в один и тот же файл
+func writeJSONAtomic(path string, v interface{}) error {
+	if err := ensureDir(path); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// sameFileVersion сообщает, что st - та же версия файла, что и прочитанная ранее prev
+// (файлы сохраняются заменой, поэтому у новой версии другой inode); nil - файла нет
+func sameFileVersion(st, prev os.FileInfo) bool {
+	if st == nil || prev == nil {
+		return st == nil && prev == nil
+	}
+	return os.SameFile(st, prev) && st.Size() == prev.Size() && st.ModTime().Equal(prev.ModTime())
+}
+
+// PutAttachment сохраняет содержимое вложения в кэш и возвращает запись о нем
+func PutAttachment(fileID string, data []byte, fileName, contentType string) (*CachedAttachment, error) {
+	if maxBytes := config.GetAttachmentCacheMaxBytes(); int64(len(data)) > maxBytes && maxBytes > 0 {
+		logger.Log.Infof("Вложение %s (%d байт) больше всего кэша, не сохраняем", fileID, len(data))
+		return nil, nil
+	}
+	return StoreAttachment(fileID, bytes.NewReader(data), fileName, contentType)
+}
+
+// StoreAttachment потоково записывает вложение в кэш, не держа его целиком в памяти.
+// Возвращает nil без ошибки, если кэш отключен или файл больше всего кэша. Ошибка чтения r прерывает запись
+func StoreAttachment(fileID string, r io.Reader, fileName, contentType string) (*CachedAttachment, error) {
+	entry, spill, err := StoreAttachmentSpill(fileID, r, fileName, contentType)
+	if spill != nil {
+		spill.Close()
+	}
+	return entry, err
+}
+
+// StoreAttachmentSpill работает как StoreAttachment, но для файла больше всего кэша возвращает
+// уже прочитанное из r начало (не больше ATTACHMENT_CACHE_MAX_MB + 1 байт) - вызывающий код
+// дочитывает остаток из r. Место на диске под начало файла освобождается при закрытии
+func StoreAttachmentSpill(fileID string, r io.Reader, fileName, contentType string) (*CachedAttachment, io.ReadCloser, error) {
+	maxBytes := config.GetAttachmentCacheMaxBytes()
+	if maxBytes == 0 || fileID == "" {
+		return nil, nil, nil
+	}
+
+	// Пишем во временный файл, одновременно считая хэш
+	dir := filepath.Join(config.GetAttachmentCacheDir(), "blobs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+	tmp, err := os.CreateTemp(dir, "incoming-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	// Читаем не больше лимита кэша: больший файл не сохраняем и не вытесняем им остальные
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, maxBytes+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if size > maxBytes {
+		logger.Log.Infof("Вложение %s больше всего кэша (%d байт), не сохраняем", fileID, maxBytes)
+		// Открытый файл остается доступен после удаления по defer
+		spill, err := os.Open(tmp.Name())
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, spill, nil
+	}
+	hexSum := hex.EncodeToString(h.Sum(nil))
+
+	attachmentsMutex.Lock()
+	defer attachmentsMutex.Unlock()
+	index, unlock, err := lockAttachmentIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	blob := attachmentBlobPath(hexSum)
+	if _, err := os.Stat(blob); errors.Is(err, fs.ErrNotExist) {
+		if err := ensureDir(blob); err != nil {
+			return nil, nil, err
+		}
+		if err := os.Rename(tmp.Name(), blob); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	now := time.Now()
+	entry := &CachedAttachment{
+		FileID:      fileID,
+		SHA256:      hexSum,
+		Size:        size,
+		FileName:    fileName,
+		ContentType: contentType,
+		StoredAt:    now,
+		LastAccess:  now,
+	}
+	index[fileID] = entry
+
+	evictAttachments(maxBytes)
+	if err := saveAttachmentIndex(); err != nil {
+		return nil, nil, err
+	}
+	logger.Log.Debugf("Вложение %s сохранено в кэш (%d байт, sha256 %s)", fileID, entry.Size, hexSum[:12])
+	copied := *entry
+	return &copied, nil, nil
+}
+
+// OpenAttachment открывает файл вложения из кэша для потокового чтения. Время обращения
+// запоминается в памяти и записывается в индекс при следующем сохранении
+func OpenAttachment(fileID string) (*CachedAttachment, *os.File, bool) {
+	attachmentsMutex.Lock()
+	defer attachmentsMutex.Unlock()
+
+	entry, ok := loadAttachmentIndex()[fileID]
+	if !ok {
+		return nil, nil, false
+	}
+	f, err := os.Open(attachmentBlobPath(entry.SHA256))
+	if err != nil {
+		logger.Log.Warnf("Файл кэша для %s недоступен: %v", fileID, err)
+		forgetAttachment(fileID)
+		return nil, nil, false
+	}
+	entry.LastAccess = time.Now()
+	attachmentsAccess[fileID] = entry.LastAccess
+	copied := *entry
+	return &copied, f, true
+}
+
+// forgetAttachment удаляет из индекса запись, файл которой пропал; вызывается под attachmentsMutex
+func forgetAttachment(fileID string) {
+	index, unlock, err := lockAttachmentIndex()
+	if err != nil {
+		logger.Log.Warnf("Не удалось обновить индекс кэша вложений: %v", err)
+		return
+	}
+	defer unlock()
+	e, ok := index[fileID]
+	if !ok {
+		return
+	}
+	// Другой процесс мог успеть сохранить файл заново
+	if _, err := os.Stat(attachmentBlobPath(e.SHA256)); err == nil {
+		return
+	}
+	delete(index, fileID)
+	if err := saveAttachmentIndex(); err != nil {
+		logger.Log.Warnf("Не удалось сохранить индекс кэша вложений: %v", err)
+	}
+}
+
+// evictAttachments удаляет давно не использованные вложения, пока кэш больше лимита;
+// вызывается под attachmentsMutex и блокировкой index.lock, поэтому видит записи всех процессов
+// и не удаляет файлы, на которые ссылаются их записи
+func evictAttachments(maxBytes int64) {
+	// Размер считаем по уникальному содержимому
+	blobs := map[string]int64{}
+	for _, e := range attachmentsIndex {
+		blobs[e.SHA256] = e.Size
+	}
+	var total int64
+	for _, size := range blobs {
+		total += size
+	}
+	if total <= maxBytes {
+		return
+	}
+
+	entries := make([]*CachedAttachment, 0, len(attachmentsIndex))
+	for _, e := range attachmentsIndex {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastAccess.Before(entries[j].LastAccess) })
+
+	refs := map[string]int{}
+	for _, e := range entries {
+		refs[e.SHA256]++
+	}
+	for _, e := range entries {
+		if total <= maxBytes {
+			break
+		}
+		delete(attachmentsIndex, e.FileID)
+		refs[e.SHA256]--
+		if refs[e.SHA256] == 0 {
+			if err := os.Remove(attachmentBlobPath(e.SHA256)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				logger.Log.Warnf("Не удалось удалить файл кэша %s: %v", e.SHA256, err)
+			}
+			total -= e.Size
+		}
+		logger.Log.Debugf("Вложение %s вытеснено из кэша", e.FileID)
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+)
+
+// ScanCheckpoint - незавершенная работа сканирования. Файл удаляется, когда все элементы обработаны
+type ScanCheckpoint struct {
+	// ScanID - идентификатор сканирования в журнале совпадений; продолженное сканирование сохраняет его
+	ScanID    string                     `json:"scanId,omitempty"`
+	StartedAt time.Time                  `json:"startedAt"`
+	Items     map[string]*ItemCheckpoint `json:"items"`
+	// Order - порядок элементов, чтобы продолжить их в том же порядке
+	Order []string `json:"order"`
+}
+
+// ItemCheckpoint - состояние обработки одного элемента RSS
+type ItemCheckpoint struct {
+	Item dto.RSSItem `json:"item"`
+	// PageDone - страница проекта проверена, уведомление о совпадении на ней отправлено
+	PageDone bool `json:"pageDone"`
+	// FilesKnown - список файлов проекта получен из стадий
+	FilesKnown bool     `json:"filesKnown"`
+	Files      []string `json:"files,omitempty"`
+	// FileInfo - описания файлов из стадий (в том же порядке, что и Files)
+	FileInfo []dto.StageFile `json:"fileInfo,omitempty"`
+	// FilesDone - проверенные файлы (уведомления о совпадениях отправлены)
+	FilesDone map[string]bool `json:"filesDone,omitempty"`
+}
+
+// Completed сообщает, что элемент обработан полностью
+func (c *ItemCheckpoint) Completed() bool {
+	if !c.PageDone || !c.FilesKnown {
+		return false
+	}
+	for _, f := range c.Files {
+		if !c.FilesDone[f] {
+			return false
+		}
+	}
+	return true
+}
+
+// StageFiles возвращает описания файлов; для контрольных точек без FileInfo известны только адреса
+func (c *ItemCheckpoint) StageFiles() []dto.StageFile {
+	if len(c.FileInfo) == len(c.Files) {
+		return c.FileInfo
+	}
+	files := make([]dto.StageFile, 0, len(c.Files))
+	for _, u := range c.Files {
+		files = append(files, dto.StageFile{URL: u})
+	}
+	return files
+}
+
+func checkpointPath() string {
+	return filepath.Join(config.GetProjectRoot(), "data", "scan_checkpoint.json")
+}
+
+// LoadCheckpoint загружает контрольную точку прерванного сканирования; nil - если ее нет
+func LoadCheckpoint() (*ScanCheckpoint, error) {
+	data, err := os.ReadFile(checkpointPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var cp ScanCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	if cp.Items == nil {
+		cp.Items = map[string]*ItemCheckpoint{}
+	}
+	return &cp, nil
+}
+
+// SaveCheckpoint атомарно сохраняет контрольную точку сканирования
+func SaveCheckpoint(cp *ScanCheckpoint) error {
+	return writeJSONAtomic(checkpointPath(), cp)
+}
+
+// ClearCheckpoint удаляет контрольную точку после полностью завершенного сканирования
+func ClearCheckpoint() error {
+	if err := os.Remove(checkpointPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
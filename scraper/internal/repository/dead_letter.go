@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+)
+
+var deadLetterMutex sync.Mutex
+
+// ResolveDataPath возвращает путь относительно корня проекта, если он не абсолютный
+func ResolveDataPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(config.GetProjectRoot(), path)
+}
+
+// AppendDeadLetter дописывает недоставленное сообщение в файл JSON Lines
+func AppendDeadLetter(path string, record interface{}) error {
+	deadLetterMutex.Lock()
+	defer deadLetterMutex.Unlock()
+
+	path = ResolveDataPath(path)
+	if err := ensureDir(path); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// Журнал совпадений - файл JSON Lines matched/matches.jsonl, в который каждое совпадение
+// дописывается одной строкой. Когда файл превышает MATCH_LOG_MAX_MB, он переименовывается
+// в matches-<время>.jsonl, а старые части сверх MATCH_LOG_KEEP удаляются
+
+const (
+	matchLogName   = "matches.jsonl"
+	matchLogPrefix = "matches-"
+	// matchLogTimeLayout - время ротации в имени части; имена сортируются по времени
+	matchLogTimeLayout = "20060102-150405.000"
+	// legacyMatchesName - прежний список совпадений, который перезаписывался целиком
+	legacyMatchesName = "file_urls.json"
+)
+
+// MatchRecord - запись журнала совпадений
+type MatchRecord struct {
+	// ID - устойчивый идентификатор: одинаков для одного файла в одном сканировании
+	ID     string `json:"id"`
+	ScanID string `json:"scanId"`
+	// FoundAt - когда совпадение записано в журнал
+	FoundAt time.Time `json:"foundAt"`
+	// NotifiedAt - когда уведомление доставлено во все каналы; пусто, если хотя бы один канал не принял его
+	NotifiedAt time.Time `json:"notifiedAt,omitzero"`
+	// NotifiedSinks - каналы, в которые уведомление доставлено при этой отправке; при повторной
+	// отправке уведомление уходит только в остальные каналы
+	NotifiedSinks []string `json:"notifiedSinks,omitempty"`
+	dto.Notification
+}
+
+// MatchQuery - условия чтения журнала; пустые поля не ограничивают выборку
+type MatchQuery struct {
+	ScanID string
+	// Since - только записи, найденные не раньше этого времени
+	Since time.Time
+	// FilesOnly - только совпадения во вложениях, без страниц проектов
+	FilesOnly bool
+}
+
+func (q MatchQuery) match(r MatchRecord) bool {
+	if q.ScanID != "" && r.ScanID != q.ScanID {
+		return false
+	}
+	if !q.Since.IsZero() && r.FoundAt.Before(q.Since) {
+		return false
+	}
+	return !q.FilesOnly || r.IsFile()
+}
+
+// legacyFileURL - запись прежнего file_urls.json (без JSON-тегов)
+type legacyFileURL struct {
+	URL         string
+	ProjectURL  string
+	Keywords    []string
+	PubDate     string
+	Title       string
+	Description string
+	FileName    string
+}
+
+var matchLogMutex sync.Mutex
+
+func matchLogPath() string {
+	return filepath.Join(config.GetMatchedDir(), matchLogName)
+}
+
+// NewScanID возвращает идентификатор сканирования: вид, время запуска и случайный суффикс
+func NewScanID(kind string) string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return kind + "-" + time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// MatchID возвращает идентификатор записи журнала для файла (или страницы проекта) в сканировании
+func MatchID(scanID, fileURL string) string {
+	sum := sha256.Sum256([]byte(scanID + "\n" + fileURL))
+	return hex.EncodeToString(sum[:8])
+}
+
+// AppendMatch дописывает совпадение в журнал. ID и FoundAt заполняются, если не заданы
+func AppendMatch(rec MatchRecord) error {
+	if rec.ID == "" {
+		rec.ID = MatchID(rec.ScanID, rec.FileURL)
+	}
+	if rec.FoundAt.IsZero() {
+		rec.FoundAt = time.Now()
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	matchLogMutex.Lock()
+	defer matchLogMutex.Unlock()
+
+	path := matchLogPath()
+	if err := ensureDir(path); err != nil {
+		return err
+	}
+	migrateLegacyMatches()
+	rotateMatchLog(path, int64(len(line)))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rotateMatchLog переименовывает журнал, если с новой строкой он превысит допустимый размер;
+// вызывается под matchLogMutex
+func rotateMatchLog(path string, adding int64) {
+	st, err := os.Stat(path)
+	if err != nil || st.Size() == 0 || st.Size()+adding <= int64(config.GetMatchLogMaxMB())<<20 {
+		return
+	}
+	rotated := filepath.Join(filepath.Dir(path), matchLogPrefix+time.Now().Format(matchLogTimeLayout)+".jsonl")
+	if err := os.Rename(path, rotated); err != nil {
+		logger.Log.Warnf("Не удалось ротировать журнал совпадений: %v", err)
+		return
+	}
+	logger.Log.Infof("🗃 Журнал совпадений ротирован: %s", filepath.Base(rotated))
+
+	keep := config.GetMatchLogKeep()
+	if keep == 0 {
+		return
+	}
+	parts := rotatedMatchLogs(filepath.Dir(path))
+	for len(parts) > keep {
+		if err := os.Remove(parts[0]); err != nil {
+			logger.Log.Warnf("Не удалось удалить старую часть журнала совпадений: %v", err)
+		}
+		parts = parts[1:]
+	}
+}
+
+// rotatedMatchLogs возвращает ротированные части журнала от старых к новым
+func rotatedMatchLogs(dir string) []string {
+	parts, _ := filepath.Glob(filepath.Join(dir, matchLogPrefix+"*.jsonl"))
+	sort.Strings(parts)
+	return parts
+}
+
+// migrateLegacyMatches переносит записи из прежнего file_urls.json в журнал, если журнала
+// еще нет; вызывается под matchLogMutex
+func migrateLegacyMatches() {
+	dir := config.GetMatchedDir()
+	legacyPath := filepath.Join(dir, legacyMatchesName)
+	st, err := os.Stat(legacyPath)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(matchLogPath()); err == nil {
+		return
+	}
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		logger.Log.Warnf("Не удалось прочитать %s: %v", legacyMatchesName, err)
+		return
+	}
+	var legacy []legacyFileURL
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		logger.Log.Warnf("Не удалось разобрать %s, перенос в журнал совпадений пропущен: %v", legacyMatchesName, err)
+		return
+	}
+
+	const scanID = "legacy"
+	var b strings.Builder
+	for _, l := range legacy {
+		line, err := json.Marshal(MatchRecord{
+			ID:      MatchID(scanID, l.URL),
+			ScanID:  scanID,
+			FoundAt: st.ModTime(),
+			Notification: dto.Notification{
+				ProjectURL:  l.ProjectURL,
+				FileURL:     l.URL,
+				FileName:    l.FileName,
+				Keywords:    l.Keywords,
+				PubDate:     l.PubDate,
+				Title:       l.Title,
+				Description: l.Description,
+			},
+		})
+		if err != nil {
+			continue
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	if err := os.WriteFile(matchLogPath(), []byte(b.String()), 0o644); err != nil {
+		logger.Log.Warnf("Не удалось перенести %s в журнал совпадений: %v", legacyMatchesName, err)
+		return
+	}
+	if err := os.Rename(legacyPath, legacyPath+".migrated"); err != nil {
+		logger.Log.Warnf("Не удалось переименовать %s: %v", legacyMatchesName, err)
+	}
+	logger.Log.Infof("🗃 Записи из %s перенесены в журнал совпадений: %d", legacyMatchesName, len(legacy))
+}
+
+// ReadMatches последовательно читает журнал от старых записей к новым, включая ротированные
+// части. Поврежденные строки (например, недописанные при падении процесса) пропускаются
+func ReadMatches(q MatchQuery) iter.Seq2[MatchRecord, error] {
+	return func(yield func(MatchRecord, error) bool) {
+		matchLogMutex.Lock()
+		migrateLegacyMatches()
+		dir := config.GetMatchedDir()
+		paths := append(rotatedMatchLogs(dir), matchLogPath())
+		matchLogMutex.Unlock()
+
+		for _, path := range paths {
+			// В части, измененной до Since, нужных записей нет
+			if st, err := os.Stat(path); err == nil && !q.Since.IsZero() && st.ModTime().Before(q.Since) {
+				continue
+			}
+			if !readMatchFile(path, q, yield) {
+				return
+			}
+		}
+	}
+}
+
+// readMatchFile передает в yield подходящие записи одного файла; false - чтение остановлено
+func readMatchFile(path string, q MatchQuery, yield func(MatchRecord, error) bool) bool {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+	if err != nil {
+		return yield(MatchRecord{}, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var rec MatchRecord
+			if jerr := json.Unmarshal(line, &rec); jerr != nil {
+				logger.Log.Warnf("Пропущена поврежденная запись журнала совпадений %s: %v", filepath.Base(path), jerr)
+			} else if q.match(rec) && !yield(rec, nil) {
+				return false
+			}
+		}
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			return yield(MatchRecord{}, err)
+		}
+	}
+}
+
+// LoadMatches читает из журнала все записи, подходящие под условия
+func LoadMatches(q MatchQuery) ([]MatchRecord, error) {
+	var records []MatchRecord
+	for rec, err := range ReadMatches(q) {
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// LastScanID возвращает идентификатор сканирования последней подходящей записи журнала;
+// пусто - таких записей нет
+func LastScanID(q MatchQuery) (string, error) {
+	var last string
+	for rec, err := range ReadMatches(q) {
+		if err != nil {
+			return last, err
+		}
+		last = rec.ScanID
+	}
+	return last, nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+)
+
+// NotifiersData структура файла с каналами доставки уведомлений
+type NotifiersData struct {
+	Sinks []dto.SinkConfig `json:"sinks"`
+}
+
+// LoadNotifierConfigs загружает список каналов доставки.
+// Если файла нет, возвращает nil - тогда используется Telegram из .env
+func LoadNotifierConfigs() ([]dto.SinkConfig, error) {
+	path := config.GetNotifiersFilePath()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var notifiersData NotifiersData
+	if err := json.Unmarshal(data, &notifiersData); err != nil {
+		return nil, err
+	}
+	return notifiersData.Sinks, nil
+}
@@ -12,19 +12,10 @@ import (
     "github.com/notenoughtea/law_scraper/internal/logger"
 )
 
+// SaveRSS атомарно сохраняет RSS: во время сканирования он перезаписывается после каждого элемента
 func SaveRSS(feed *dto.RSS) error {
     path := filepath.Join(config.GetProjectRoot(), "data", "rss.json")
-    if err := ensureDir(path); err != nil {
-        return err
-    }
-    f, err := os.Create(path)
-    if err != nil {
-        return err
-    }
-    defer f.Close()
-    enc := json.NewEncoder(f)
-    enc.SetIndent("", "  ")
-    return enc.Encode(feed)
+    return writeJSONAtomic(path, feed)
 }
 
 // LoadPreviousRSS загружает предыдущий сохранённый RSS
@@ -44,6 +35,7 @@ func LoadPreviousRSS() (*dto.RSS, error) {
     if err := json.Unmarshal(data, &feed); err != nil {
         return nil, err
     }
+    feed.ParseItems()
     
     return &feed, nil
 }
@@ -48,36 +48,6 @@ func LoadPages() ([]dto.ListResponse, error) {
 	return pages, nil
 }
 
-// FileURLWithKeywords содержит URL файла и найденные в нём ключевые слова
-type FileURLWithKeywords struct {
-	URL         string
-	ProjectURL  string
-	Keywords    []string
-	PubDate     string
-	Title       string
-	Description string
-}
-
-// SaveFileURLs сохраняет список URL-ов файлов с найденными словами в JSON файле
-func SaveFileURLs(files []FileURLWithKeywords) error {
-	dir := config.GetMatchedDir()
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
-	}
-
-	// Сохраняем в JSON формате для удобства
-	path := filepath.Join(dir, "file_urls.json")
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	return enc.Encode(files)
-}
-
 // ClearPagesData удаляет файл pages.json
 func ClearPagesData() error {
 	storage := config.GetStoragePath()
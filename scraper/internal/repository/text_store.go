@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/filelock"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// StoredText - запись хранилища извлеченных текстов. Сам текст (в нижнем регистре)
+// лежит сжатым в <каталог>/<fileId>.txt.gz
+type StoredText struct {
+	FileID     string    `json:"fileId"`
+	FileURL    string    `json:"fileUrl"`
+	FileName   string    `json:"fileName,omitempty"`
+	ProjectID  string    `json:"projectId,omitempty"`
+	ProjectURL string    `json:"projectUrl"`
+	Title      string    `json:"title,omitempty"`
+	Size       int64     `json:"size"`
+	StoredAt   time.Time `json:"storedAt"`
+}
+
+var (
+	textsMutex sync.Mutex
+	textsIndex map[string]*StoredText
+	// textsIndexFile - прочитанный файл индекса: другие процессы заменяют его при сохранении
+	textsIndexFile os.FileInfo
+)
+
+func textIndexPath() string {
+	return filepath.Join(config.GetTextStoreDir(), "index.json")
+}
+
+func textLockPath() string {
+	return filepath.Join(config.GetTextStoreDir(), "index.lock")
+}
+
+func textPath(fileID string) string {
+	return filepath.Join(config.GetTextStoreDir(), fileID+".txt.gz")
+}
+
+// loadTextIndex загружает индекс хранилища и перечитывает его, если файл заменен
+// другим процессом (cron, бот, cmd/backfill); вызывается под textsMutex
+func loadTextIndex() map[string]*StoredText {
+	st, statErr := os.Stat(textIndexPath())
+	if textsIndex != nil && sameFileVersion(st, textsIndexFile) {
+		return textsIndex
+	}
+	textsIndex = map[string]*StoredText{}
+	textsIndexFile = st
+
+	if statErr != nil {
+		if !errors.Is(statErr, fs.ErrNotExist) {
+			logger.Log.Warnf("Не удалось прочитать индекс хранилища текстов: %v", statErr)
+		}
+		return textsIndex
+	}
+	data, err := os.ReadFile(textIndexPath())
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Log.Warnf("Не удалось прочитать индекс хранилища текстов: %v", err)
+		}
+		return textsIndex
+	}
+	var entries []*StoredText
+	if err := json.Unmarshal(data, &entries); err != nil {
+		logger.Log.Warnf("Индекс хранилища текстов поврежден, начинаем заново: %v", err)
+		return textsIndex
+	}
+	for _, e := range entries {
+		textsIndex[e.FileID] = e
+	}
+	return textsIndex
+}
+
+// saveTextIndex атомарно записывает индекс; вызывается под textsMutex и блокировкой index.lock
+func saveTextIndex() error {
+	entries := make([]*StoredText, 0, len(textsIndex))
+	for _, e := range textsIndex {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].FileID < entries[j].FileID })
+	if err := writeJSONAtomic(textIndexPath(), entries); err != nil {
+		return err
+	}
+	textsIndexFile, _ = os.Stat(textIndexPath())
+	return nil
+}
+
+// TextWriter потоково сжимает текст документа во временный файл; запись появляется
+// в хранилище только после Commit
+type TextWriter struct {
+	meta StoredText
+	file *os.File
+	gz   *gzip.Writer
+}
+
+// CreateText начинает запись текста документа. Возвращает nil без ошибки,
+// если хранение текстов отключено (TEXT_STORE_DAYS=0) или у документа нет ID
+func CreateText(meta StoredText) (*TextWriter, error) {
+	if config.GetTextStoreDays() == 0 || meta.FileID == "" {
+		return nil, nil
+	}
+	dir := config.GetTextStoreDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "text-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &TextWriter{meta: meta, file: f, gz: gzip.NewWriter(f)}, nil
+}
+
+func (w *TextWriter) Write(p []byte) (int, error) {
+	n, err := w.gz.Write(p)
+	w.meta.Size += int64(n)
+	return n, err
+}
+
+// Commit завершает запись, добавляет текст в индекс и удаляет тексты старше TEXT_STORE_DAYS
+func (w *TextWriter) Commit() error {
+	if err := w.gz.Close(); err != nil {
+		w.Abort()
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+	if err := os.Rename(w.file.Name(), textPath(w.meta.FileID)); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+
+	textsMutex.Lock()
+	defer textsMutex.Unlock()
+	// Под блокировкой индекс перечитывается, чтобы не потерять тексты, сохраненные другими процессами
+	unlock, err := filelock.Lock(textLockPath(), true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	index := loadTextIndex()
+	w.meta.StoredAt = time.Now()
+	meta := w.meta
+	index[meta.FileID] = &meta
+	pruneTexts(index, time.Now().AddDate(0, 0, -config.GetTextStoreDays()))
+	return saveTextIndex()
+}
+
+// Abort отменяет запись и удаляет временный файл
+func (w *TextWriter) Abort() {
+	w.gz.Close()
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+// pruneTexts удаляет тексты, сохраненные раньше before; вызывается под textsMutex и блокировкой index.lock
+func pruneTexts(index map[string]*StoredText, before time.Time) {
+	for id, e := range index {
+		if e.StoredAt.Before(before) {
+			if err := os.Remove(textPath(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				logger.Log.Warnf("Не удалось удалить текст %s: %v", id, err)
+				continue
+			}
+			delete(index, id)
+		}
+	}
+}
+
+// ListTexts возвращает тексты, сохраненные не раньше since, от новых к старым
+func ListTexts(since time.Time) []StoredText {
+	textsMutex.Lock()
+	defer textsMutex.Unlock()
+	var list []StoredText
+	for _, e := range loadTextIndex() {
+		if !e.StoredAt.Before(since) {
+			list = append(list, *e)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StoredAt.After(list[j].StoredAt) })
+	return list
+}
+
+// OpenText открывает сохраненный текст документа для чтения
+func OpenText(fileID string) (io.ReadCloser, error) {
+	f, err := os.Open(textPath(fileID))
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &textReader{Reader: gz, file: f}, nil
+}
+
+// textReader закрывает и распаковщик, и файл
+type textReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *textReader) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
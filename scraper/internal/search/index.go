@@ -0,0 +1,462 @@
+// Package search - встроенный полнотекстовый индекс документов: обратный индекс
+// по основам русских слов с ранжированием BM25. Индекс хранится на диске журналом
+// JSON Lines (добавления и удаления документов), который читается при открытии
+// и периодически сжимается. Журнал пишут несколько процессов (бот, cron, cmd/backfill):
+// запись и сжатие выполняются под блокировкой файла index.lock, а перед каждым
+// изменением и поиском индекс дочитывает то, что дописали другие процессы
+package search
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/filelock"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+// Параметры BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Doc - документ индекса
+type Doc struct {
+	FileID     string    `json:"fileId"`
+	FileURL    string    `json:"fileUrl"`
+	FileName   string    `json:"fileName,omitempty"`
+	ProjectID  string    `json:"projectId,omitempty"`
+	ProjectURL string    `json:"projectUrl"`
+	Title      string    `json:"title,omitempty"`
+	Length     int       `json:"length"`
+	IndexedAt  time.Time `json:"indexedAt"`
+}
+
+// Result - найденный документ с оценкой релевантности и совпавшими терминами запроса
+type Result struct {
+	Doc   Doc
+	Score float64
+	Terms []string
+}
+
+type posting struct {
+	doc uint32
+	tf  uint32
+}
+
+// record - строка журнала индекса
+type record struct {
+	Op     string         `json:"op"` // add или remove
+	Doc    *Doc           `json:"doc,omitempty"`
+	FileID string         `json:"fileId,omitempty"`
+	Terms  map[string]int `json:"terms,omitempty"`
+}
+
+// Index - обратный индекс. Безопасен для одновременного использования
+type Index struct {
+	mu   sync.RWMutex
+	path string
+	// file и offset - прочитанный файл журнала и сколько байт из него применено
+	file     os.FileInfo
+	offset   int64
+	docs     []*Doc // nil - документ удален
+	byFile   map[string]uint32
+	postings map[string][]posting
+	live     int
+	deleted  int
+	totalLen int64
+}
+
+// Open загружает индекс из каталога dir (или создает пустой)
+func Open(dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	ix := &Index{path: filepath.Join(dir, "index.jsonl")}
+	ix.reset()
+
+	unlock, err := filelock.Lock(ix.lockPath(), false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := ix.loadLocked(); err != nil {
+		return nil, err
+	}
+	logger.Log.Infof("🔍 Индекс поиска загружен: документов %d, терминов %d", ix.live, len(ix.postings))
+	return ix, nil
+}
+
+func (ix *Index) lockPath() string {
+	return filepath.Join(filepath.Dir(ix.path), "index.lock")
+}
+
+// loadLocked применяет записи журнала, которых индекс еще не видел. Если журнал заменен
+// (другой процесс сжал его) или стал короче, индекс перечитывается целиком.
+// Вызывается под ix.mu и блокировкой файла
+func (ix *Index) loadLocked() error {
+	f, err := os.Open(ix.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if ix.file != nil {
+			ix.reset()
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if ix.file == nil || !os.SameFile(ix.file, st) || st.Size() < ix.offset {
+		ix.reset()
+	}
+	ix.file = st
+	if st.Size() == ix.offset {
+		return nil
+	}
+	if _, err := f.Seek(ix.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		ix.offset += int64(len(line))
+		if len(line) > 0 {
+			var rec record
+			if jerr := json.Unmarshal(line, &rec); jerr != nil {
+				// Недописанная строка после падения процесса
+				logger.Log.Warnf("Пропущена поврежденная запись индекса поиска: %v", jerr)
+			} else {
+				ix.apply(rec)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unchanged сообщает, что с последнего чтения журнал не менялся; вызывается под ix.mu
+func (ix *Index) unchanged() bool {
+	st, err := os.Stat(ix.path)
+	if err != nil {
+		return ix.file == nil && errors.Is(err, fs.ErrNotExist)
+	}
+	return ix.file != nil && os.SameFile(ix.file, st) && st.Size() == ix.offset
+}
+
+// refresh дочитывает изменения журнала, сделанные другими процессами
+func (ix *Index) refresh() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.unchanged() {
+		return nil
+	}
+	unlock, err := filelock.Lock(ix.lockPath(), false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return ix.loadLocked()
+}
+
+// lockForWrite берет исключительную блокировку журнала и дочитывает его, чтобы изменение
+// применялось к актуальному индексу; вызывается под ix.mu
+func (ix *Index) lockForWrite() (func(), error) {
+	unlock, err := filelock.Lock(ix.lockPath(), true)
+	if err != nil {
+		return nil, err
+	}
+	if err := ix.loadLocked(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+func (ix *Index) reset() {
+	ix.file, ix.offset = nil, 0
+	ix.docs = nil
+	ix.byFile = map[string]uint32{}
+	ix.postings = map[string][]posting{}
+	ix.live, ix.deleted, ix.totalLen = 0, 0, 0
+}
+
+// apply применяет запись журнала к индексу в памяти; вызывается под ix.mu
+func (ix *Index) apply(rec record) {
+	switch rec.Op {
+	case "add":
+		if rec.Doc == nil {
+			return
+		}
+		ix.removeLocked(rec.Doc.FileID)
+		id := uint32(len(ix.docs))
+		doc := *rec.Doc
+		ix.docs = append(ix.docs, &doc)
+		ix.byFile[doc.FileID] = id
+		for term, tf := range rec.Terms {
+			ix.postings[term] = append(ix.postings[term], posting{doc: id, tf: uint32(tf)})
+		}
+		ix.live++
+		ix.totalLen += int64(doc.Length)
+	case "remove":
+		ix.removeLocked(rec.FileID)
+	}
+}
+
+// removeLocked помечает документ удаленным; его записи в списках терминов
+// пропускаются при поиске и убираются при сжатии
+func (ix *Index) removeLocked(fileID string) bool {
+	id, ok := ix.byFile[fileID]
+	if !ok {
+		return false
+	}
+	ix.totalLen -= int64(ix.docs[id].Length)
+	ix.docs[id] = nil
+	delete(ix.byFile, fileID)
+	ix.live--
+	ix.deleted++
+	return true
+}
+
+// Add добавляет документ (заменяя прежнюю версию с тем же FileID) с частотами терминов из c
+func (ix *Index) Add(doc Doc, c *Counter) error {
+	c.Close()
+	doc.Length = c.length
+	if doc.IndexedAt.IsZero() {
+		doc.IndexedAt = time.Now()
+	}
+	rec := record{Op: "add", Doc: &doc, Terms: c.terms}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	unlock, err := ix.lockForWrite()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	ix.apply(rec)
+	return ix.appendLocked(rec)
+}
+
+// Remove удаляет документ из индекса
+func (ix *Index) Remove(fileID string) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	unlock, err := ix.lockForWrite()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if !ix.removeLocked(fileID) {
+		return nil
+	}
+	return ix.appendLocked(record{Op: "remove", FileID: fileID})
+}
+
+// Prune удаляет документы, проиндексированные раньше before, и возвращает их число
+func (ix *Index) Prune(before time.Time) (int, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	unlock, err := ix.lockForWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	removed := 0
+	for _, doc := range ix.docs {
+		if doc != nil && doc.IndexedAt.Before(before) {
+			fileID := doc.FileID
+			if !ix.removeLocked(fileID) {
+				continue
+			}
+			if err := ix.appendLocked(record{Op: "remove", FileID: fileID}); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Len возвращает число документов в индексе
+func (ix *Index) Len() int {
+	if err := ix.refresh(); err != nil {
+		logger.Log.Warnf("Не удалось перечитать индекс поиска: %v", err)
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.live
+}
+
+// appendLocked дописывает запись в журнал и сжимает его, если удаленных документов
+// стало больше, чем живых; вызывается под ix.mu и исключительной блокировкой файла
+func (ix *Index) appendLocked(rec record) error {
+	if ix.deleted > 100 && ix.deleted > ix.live {
+		return ix.compactLocked()
+	}
+	f, err := os.OpenFile(ix.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	// Под блокировкой никто другой не пишет - журнал прочитан до конца
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	ix.file, ix.offset = st, st.Size()
+	return f.Close()
+}
+
+// compactLocked переписывает журнал только живыми документами и перестраивает индекс
+// в памяти без удаленных; вызывается под ix.mu и исключительной блокировкой файла, поэтому
+// документы, добавленные другими процессами, уже прочитаны и не теряются
+func (ix *Index) compactLocked() error {
+	terms := make(map[uint32]map[string]int, ix.live)
+	for term, list := range ix.postings {
+		for _, p := range list {
+			if ix.docs[p.doc] == nil {
+				continue
+			}
+			if terms[p.doc] == nil {
+				terms[p.doc] = map[string]int{}
+			}
+			terms[p.doc][term] = int(p.tf)
+		}
+	}
+
+	tmp := ix.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	var recs []record
+	for id, doc := range ix.docs {
+		if doc == nil {
+			continue
+		}
+		rec := record{Op: "add", Doc: doc, Terms: terms[uint32(id)]}
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+		recs = append(recs, rec)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		return err
+	}
+	st, err := os.Stat(ix.path)
+	if err != nil {
+		return err
+	}
+
+	ix.reset()
+	for _, rec := range recs {
+		ix.apply(rec)
+	}
+	ix.file, ix.offset = st, st.Size()
+	logger.Log.Infof("🔍 Индекс поиска сжат: документов %d", ix.live)
+	return nil
+}
+
+// Search ищет документы по запросу (любые из слов, больше совпавших слов - выше)
+// и возвращает не больше limit лучших по BM25
+func (ix *Index) Search(query string, limit int) []Result {
+	terms := QueryTerms(query)
+	if err := ix.refresh(); err != nil {
+		logger.Log.Warnf("Не удалось перечитать индекс поиска: %v", err)
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if ix.live == 0 || len(terms) == 0 {
+		return nil
+	}
+
+	avgLen := float64(ix.totalLen) / float64(ix.live)
+	if avgLen == 0 {
+		avgLen = 1
+	}
+	scores := map[uint32]*Result{}
+	for _, term := range terms {
+		list := ix.postings[term]
+		df := 0
+		for _, p := range list {
+			if ix.docs[p.doc] != nil {
+				df++
+			}
+		}
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (float64(ix.live)-float64(df)+0.5)/(float64(df)+0.5))
+		for _, p := range list {
+			doc := ix.docs[p.doc]
+			if doc == nil {
+				continue
+			}
+			tf := float64(p.tf)
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(doc.Length)/avgLen)
+			res := scores[p.doc]
+			if res == nil {
+				res = &Result{Doc: *doc}
+				scores[p.doc] = res
+			}
+			res.Score += idf * tf * (bm25K1 + 1) / (tf + norm)
+			res.Terms = append(res.Terms, term)
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for _, res := range scores {
+		results = append(results, *res)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Doc.IndexedAt.After(results[j].Doc.IndexedAt)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
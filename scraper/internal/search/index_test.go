@@ -0,0 +1,143 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// counterOf считает термины текста, передавая его частями по size байт
+func counterOf(text string, size int) *Counter {
+	c := NewCounter()
+	data := []byte(text)
+	for len(data) > 0 {
+		n := min(size, len(data))
+		c.Write(data[:n])
+		data = data[n:]
+	}
+	return c
+}
+
+// fileIDs возвращает FileID результатов по порядку
+func fileIDs(results []Result) []string {
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Doc.FileID)
+	}
+	return ids
+}
+
+func openTestIndex(t *testing.T, docs map[string]string) (*Index, string) {
+	t.Helper()
+	dir := t.TempDir()
+	ix, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	// Добавляем в порядке имен, чтобы время индексации было предсказуемым
+	ids := make([]string, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range ids {
+		doc := Doc{FileID: id, FileURL: "https://regulation.gov.ru/GetFile/" + id, IndexedAt: base.Add(time.Duration(i) * time.Hour)}
+		if err := ix.Add(doc, counterOf(docs[id], len(docs[id]))); err != nil {
+			t.Fatalf("Add(%s): %v", id, err)
+		}
+	}
+	return ix, dir
+}
+
+func TestSearchBM25Ordering(t *testing.T) {
+	filler := strings.Repeat("порядок применения положений ", 30)
+	docs := map[string]string{
+		"rates":    "налоговые ставки и налоговый кодекс: налоговая ставка",
+		"code":     "налоговый кодекс",
+		"longcode": "налоговый кодекс " + filler,
+		"cargo":    "перевозка грузов автомобильным транспортом",
+		"cargo2":   "перевозки грузов перевозки пассажиров перевозку",
+	}
+	ix, _ := openTestIndex(t, docs)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"больше совпавших слов - выше", "налоговая ставка", []string{"rates", "code", "longcode"}},
+		{"частота термина повышает оценку", "перевозка", []string{"cargo2", "cargo"}},
+		{"короткий документ выше длинного", "кодекс", []string{"code", "rates", "longcode"}},
+		{"разные формы слова", "налоговыми", []string{"rates", "code", "longcode"}},
+		{"стоп-слова не ищутся", "и по для", nil},
+		{"нет совпадений", "лицензирование", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fileIDs(ix.Search(tt.query, 0))
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, ожидалось %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	if got := fileIDs(ix.Search("налоговый", 2)); len(got) != 2 {
+		t.Errorf("limit 2: получено %v", got)
+	}
+}
+
+func TestIndexRemovePruneAndReopen(t *testing.T) {
+	ix, dir := openTestIndex(t, map[string]string{
+		"a": "маркировка товаров",
+		"b": "маркировка лекарств",
+		"c": "маркировка табака",
+	})
+
+	if err := ix.Remove("b"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	// a проиндексирован в 00:00, c - в 02:00
+	removed, err := ix.Prune(time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC))
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune = %d, %v; ожидался 1 удаленный документ", removed, err)
+	}
+	if got := fileIDs(ix.Search("маркировка", 0)); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("после удаления найдено %v, ожидалось [c]", got)
+	}
+
+	// Журнал индекса переживает повторное открытие
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("повторный Open: %v", err)
+	}
+	if reopened.Len() != 1 {
+		t.Errorf("после повторного открытия документов %d, ожидался 1", reopened.Len())
+	}
+	if got := fileIDs(reopened.Search("маркировки", 0)); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("после повторного открытия найдено %v, ожидалось [c]", got)
+	}
+}
+
+func TestCounterAcrossChunks(t *testing.T) {
+	// Counter получает текст уже в нижнем регистре
+	text := "налоговый кодекс устанавливает налоговые ставки; перевозка грузов и перевозки"
+	want := counterOf(text, len(text))
+	want.Close()
+	for _, size := range []int{1, 2, 5, 13} {
+		c := counterOf(text, size)
+		c.Close()
+		if c.length != want.length {
+			t.Errorf("части по %d байт: слов %d, ожидалось %d", size, c.length, want.length)
+		}
+		for term, n := range want.terms {
+			if c.terms[term] != n {
+				t.Errorf("части по %d байт: %q встречается %d раз, ожидалось %d", size, term, c.terms[term], n)
+			}
+		}
+	}
+}
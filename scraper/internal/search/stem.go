@@ -0,0 +1,145 @@
+package search
+
+import "strings"
+
+// Стеммер русского языка по алгоритму Snowball (Портера): отрезает окончания, чтобы
+// "налоговый", "налоговая" и "налоговых" попадали в индекс одним термином.
+// Слова не на кириллице возвращаются без изменений
+
+var (
+	gerund1      = []string{"в", "вши", "вшись"}
+	gerund2      = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	adjective    = []string{"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом", "его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	participle1  = []string{"ем", "нн", "вш", "ющ", "щ"}
+	participle2  = []string{"ивш", "ывш", "ующ"}
+	reflexive    = []string{"ся", "сь"}
+	verb1        = []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"}
+	verb2        = []string{"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен", "ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю"}
+	noun         = []string{"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й", "иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я"}
+	derivational = []string{"ост", "ость"}
+	superlative  = []string{"ейш", "ейше"}
+)
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// Stem возвращает основу слова в нижнем регистре
+func Stem(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ё", "е"))
+	if len(w) < 3 || !isCyrillic(w) {
+		return string(w)
+	}
+
+	// RV - часть после первой гласной, R2 - по определению Snowball
+	rv := len(w)
+	for i, r := range w {
+		if isVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r1 := region(w, 0)
+	r2 := region(w, r1)
+
+	// Шаг 1
+	if s, ok := cut(w, rv, gerund2, false); ok {
+		w = s
+	} else if s, ok := cut(w, rv, gerund1, true); ok {
+		w = s
+	} else {
+		if s, ok := cut(w, rv, reflexive, false); ok {
+			w = s
+		}
+		if s, ok := cut(w, rv, adjective, false); ok {
+			w = s
+			if s, ok := cut(w, rv, participle2, false); ok {
+				w = s
+			} else if s, ok := cut(w, rv, participle1, true); ok {
+				w = s
+			}
+		} else if s, ok := cutVerb(w, rv); ok {
+			w = s
+		} else if s, ok := cut(w, rv, noun, false); ok {
+			w = s
+		}
+	}
+
+	// Шаг 2
+	if s, ok := cut(w, rv, []string{"и"}, false); ok {
+		w = s
+	}
+	// Шаг 3
+	if s, ok := cut(w, r2, derivational, false); ok {
+		w = s
+	}
+	// Шаг 4
+	if s, ok := cut(w, rv, superlative, false); ok {
+		w = s
+	}
+	if s, ok := cut(w, rv, []string{"нн"}, false); ok {
+		w = append(s, 'н')
+	} else if s, ok := cut(w, rv, []string{"ь"}, false); ok {
+		w = s
+	}
+	return string(w)
+}
+
+// cutVerb отрезает глагольное окончание, выбирая самое длинное из обеих групп
+func cutVerb(w []rune, rv int) ([]rune, bool) {
+	s2, ok2 := cut(w, rv, verb2, false)
+	s1, ok1 := cut(w, rv, verb1, true)
+	switch {
+	case ok1 && ok2:
+		if len(s1) < len(s2) {
+			return s1, true
+		}
+		return s2, true
+	case ok2:
+		return s2, true
+	case ok1:
+		return s1, true
+	}
+	return w, false
+}
+
+// region возвращает начало области R1 (после первой согласной, следующей за гласной),
+// начиная поиск с from
+func region(w []rune, from int) int {
+	for i := from + 1; i < len(w); i++ {
+		if !isVowel(w[i]) && isVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// cut отрезает самое длинное из окончаний, целиком лежащее в области от start.
+// afterAYa - окончание должно идти после "а" или "я" (они остаются)
+func cut(w []rune, start int, endings []string, afterAYa bool) ([]rune, bool) {
+	best := -1
+	for _, e := range endings {
+		n := len([]rune(e))
+		pos := len(w) - n
+		if pos < start || n <= best || string(w[pos:]) != e {
+			continue
+		}
+		if afterAYa && (pos-1 < start || w[pos-1] != 'а' && w[pos-1] != 'я') {
+			continue
+		}
+		best = n
+	}
+	if best < 0 {
+		return w, false
+	}
+	return w[:len(w)-best], true
+}
+
+func isCyrillic(w []rune) bool {
+	for _, r := range w {
+		if r >= 'а' && r <= 'я' {
+			return true
+		}
+	}
+	return false
+}
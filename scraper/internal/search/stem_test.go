@@ -0,0 +1,40 @@
+package search
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		name string
+		word string
+		want string
+	}{
+		{"прилагательное мужского рода", "налоговый", "налогов"},
+		{"прилагательное женского рода", "налоговая", "налогов"},
+		{"прилагательное во множественном числе", "налоговых", "налогов"},
+		{"существительное, им. падеж", "перевозка", "перевозк"},
+		{"существительное, род. падеж", "перевозки", "перевозк"},
+		{"существительное, вин. падеж", "перевозку", "перевозк"},
+		{"окончание -ов", "документов", "документ"},
+		{"окончание -ы", "тарифы", "тариф"},
+		{"окончание -ие", "регулирование", "регулирован"},
+		{"окончание -ия", "организация", "организац"},
+		{"окончание -ии", "организации", "организац"},
+		{"ё заменяется на е", "ёлка", "елк"},
+		{"превосходная степень", "красивейший", "красив"},
+		{"возвратный глагол", "одеваться", "одева"},
+		{"глагол прошедшего времени", "читали", "чита"},
+		{"деепричастие", "читавши", "чита"},
+		{"причастие", "вывезенных", "вывезен"},
+		{"-ость вне R2 остается", "ценность", "ценност"},
+		{"короткое слово", "дом", "дом"},
+		{"латиница без изменений", "abc", "abc"},
+		{"число без изменений", "2024", "2024"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Stem(tt.word); got != tt.want {
+				t.Errorf("Stem(%q) = %q, ожидалось %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
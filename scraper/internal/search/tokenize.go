@@ -0,0 +1,163 @@
+package search
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTokenLen - более длинные последовательности букв (base64, мусор из бинарных файлов) не индексируются
+const maxTokenLen = 40
+
+// stopWords - служебные слова, которые не несут смысла для поиска
+var stopWords = map[string]bool{
+	"и": true, "в": true, "во": true, "не": true, "на": true, "с": true, "со": true, "по": true,
+	"к": true, "ко": true, "о": true, "об": true, "от": true, "до": true, "из": true, "за": true,
+	"для": true, "при": true, "или": true, "а": true, "но": true, "что": true, "как": true,
+	"это": true, "то": true, "также": true, "же": true, "ли": true, "бы": true, "у": true,
+	"его": true, "ее": true, "их": true, "он": true, "она": true, "они": true, "оно": true,
+	"который": true, "которые": true, "которых": true, "которой": true, "которым": true,
+	"года": true, "г": true, "ст": true, "п": true, "пп": true, "ч": true, "n": true,
+}
+
+// Token - слово текста: основа и смещения исходного слова в байтах
+type Token struct {
+	Term       string
+	Start, End int
+}
+
+// Tokens разбивает текст в нижнем регистре на термины индекса
+func Tokens(text string) []Token {
+	var tokens []Token
+	eachToken(text, func(t Token) bool {
+		tokens = append(tokens, t)
+		return true
+	})
+	return tokens
+}
+
+// FindTerm возвращает первое слово текста, основа которого входит в terms
+func FindTerm(text string, terms []string) (Token, bool) {
+	want := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		want[t] = true
+	}
+	var found Token
+	ok := false
+	eachToken(text, func(t Token) bool {
+		if want[t.Term] {
+			found, ok = t, true
+			return false
+		}
+		return true
+	})
+	return found, ok
+}
+
+// eachToken вызывает fn для каждого термина текста, пока fn возвращает true
+func eachToken(text string, fn func(Token) bool) {
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			if term, ok := termOf(text[start:i]); ok && !fn(Token{Term: term, Start: start, End: i}) {
+				return
+			}
+			start = -1
+		}
+	}
+	if start >= 0 {
+		if term, ok := termOf(text[start:]); ok {
+			fn(Token{Term: term, Start: start, End: len(text)})
+		}
+	}
+}
+
+// QueryTerms возвращает различные термины поискового запроса
+func QueryTerms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, t := range Tokens(toLower(query)) {
+		if !seen[t.Term] {
+			seen[t.Term] = true
+			terms = append(terms, t.Term)
+		}
+	}
+	return terms
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// termOf превращает слово в термин; ok = false для стоп-слов и слишком длинных слов
+func termOf(word string) (string, bool) {
+	if stopWords[word] || utf8.RuneCountInString(word) > maxTokenLen {
+		return "", false
+	}
+	return Stem(word), true
+}
+
+func toLower(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
+
+// Counter потоково считает частоты терминов документа: текст можно передавать
+// частями произвольного размера, слово на границе частей не теряется
+type Counter struct {
+	terms   map[string]int
+	length  int
+	pending []byte
+}
+
+func NewCounter() *Counter {
+	return &Counter{terms: map[string]int{}}
+}
+
+func (c *Counter) Write(p []byte) (int, error) {
+	data := append(c.pending, p...)
+	// Последнее слово может продолжиться в следующей части - оставляем его
+	cut := len(data)
+	for cut > 0 {
+		r, size := utf8.DecodeLastRune(data[:cut])
+		if r == utf8.RuneError && size == 1 {
+			// Символ разрезан между частями
+			cut--
+			continue
+		}
+		if !isWordRune(r) {
+			break
+		}
+		cut -= size
+	}
+	c.add(string(data[:cut]))
+	c.pending = append(c.pending[:0:0], data[cut:]...)
+	if len(c.pending) > maxTokenLen*utf8.UTFMax {
+		// Слишком длинное "слово" - не индексируем
+		c.pending = c.pending[:0]
+	}
+	return len(p), nil
+}
+
+// Close учитывает последнее слово
+func (c *Counter) Close() error {
+	c.add(string(c.pending))
+	c.pending = nil
+	return nil
+}
+
+func (c *Counter) add(text string) {
+	eachToken(text, func(t Token) bool {
+		c.terms[t.Term]++
+		c.length++
+		return true
+	})
+}
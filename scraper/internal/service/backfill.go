@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/clients"
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+)
+
+// backfillPageSize - размер страницы списка проектов при ретроспективном сканировании
+const backfillPageSize = 50
+
+// BackfillRange - какие проекты просканировать повторно: по диапазону числовых ID
+// и (или) по дате создания. Нулевые границы не ограничивают диапазон
+type BackfillRange struct {
+	FromID, ToID int
+	From, To     time.Time
+	// Notify - отправлять новые совпадения в каналы доставки; иначе только отчет
+	Notify bool
+}
+
+// filter возвращает фильтр списка проектов для диапазона
+func (r BackfillRange) filter() clients.ActsFilter {
+	return clients.ActsFilter{FromID: r.FromID, ToID: r.ToID, From: r.From, To: r.To}
+}
+
+// BackfillReport - итог ретроспективного сканирования
+type BackfillReport struct {
+	Projects int
+	// Truncated - список проектов просмотрен не до конца диапазона (BACKFILL_MAX_PAGES)
+	Truncated bool
+	// Matches - новые совпадения (отправлены, если включен Notify)
+	Matches []dto.Notification
+	// AlreadyDelivered - совпадения в файлах, о которых уже уведомляли раньше
+	AlreadyDelivered []dto.Notification
+}
+
+// backfillProgress - учет шагов для ретроспективного сканирования: контрольная точка
+// и rss.json не трогаются, каждый запуск проверяет диапазон заново
+type backfillProgress struct{}
+
+func (backfillProgress) state(string) repository.ItemCheckpoint { return repository.ItemCheckpoint{} }
+func (backfillProgress) pageDone(string)                        {}
+func (backfillProgress) filesFound(string, []dto.StageFile)     {}
+func (backfillProgress) fileDone(string, string)                {}
+
+// Backfill сканирует старые проекты из публичного списка regulation.gov.ru по текущим ключевым
+// словам. Список идет от новых проектов к старым, поэтому просмотр останавливается на первой
+// странице, целиком лежащей ниже диапазона, или через BACKFILL_MAX_PAGES страниц.
+// О файлах, уведомления о которых уже доставлены (журнал matched/matches.jsonl), повторно не уведомляем,
+// совпадения на страницах проектов только попадают в отчет
+func Backfill(ctx context.Context, r BackfillRange) (*BackfillReport, error) {
+	items, err := backfillItems(ctx, r)
+	truncated := errors.Is(err, clients.ErrActsTruncated)
+	if truncated {
+		err = nil
+	}
+	if err != nil && len(items) == 0 {
+		return nil, err
+	}
+	if err != nil {
+		logger.Log.Warnf("Список проектов загружен не полностью: %v", err)
+	}
+	report := &BackfillReport{Projects: len(items), Truncated: truncated}
+	logger.Log.Infof("🗂 Проектов в диапазоне: %d", len(items))
+	if len(items) == 0 || ctx.Err() != nil {
+		return report, ctx.Err()
+	}
+
+	// delivered - файлы, уведомления о которых доставлены во все каналы;
+	// sinks - каналы, уже принявшие уведомление о файле при частично неудачной доставке
+	delivered := map[string]bool{}
+	sinks := map[string]map[string]bool{}
+	for rec, err := range repository.ReadMatches(repository.MatchQuery{FilesOnly: true}) {
+		if err != nil {
+			logger.Log.Warnf("Не удалось загрузить историю совпадений: %v", err)
+			break
+		}
+		// Неудачные доставки и записи без отправки (cmd/scraper) можно отправить снова
+		if !rec.NotifiedAt.IsZero() {
+			delivered[rec.FileURL] = true
+		}
+		for _, name := range rec.NotifiedSinks {
+			if sinks[rec.FileURL] == nil {
+				sinks[rec.FileURL] = map[string]bool{}
+			}
+			sinks[rec.FileURL][name] = true
+		}
+	}
+
+	m := compileKeywords()
+	var notifier clients.Notifier
+	if r.Notify {
+		notifier = LoadNotifier()
+	}
+
+	scanID := repository.NewScanID("backfill")
+	var sent int64
+	var sentMutex sync.Mutex
+	runScanPipeline(ctx, items, m, backfillProgress{}, func(n dto.Notification) {
+		if n.IsFile() && delivered[n.FileURL] {
+			logger.Log.Infof("↩️ Уже отправлялось раньше: %s", n.FileURL)
+			report.AlreadyDelivered = append(report.AlreadyDelivered, n)
+			return
+		}
+		report.Matches = append(report.Matches, n)
+		if notifier != nil && n.IsFile() {
+			delivered[n.FileURL] = true
+			if len(sinks[n.FileURL]) > 0 {
+				logger.Log.Infof("↩️ Повторная отправка для %s только в недоставленные каналы", n.FileURL)
+			}
+			sendNotificationImmediately(ctx, notifier, scanID, n, sinks[n.FileURL], &sent, &sentMutex)
+		}
+	})
+	clients.LogHostStats()
+
+	if notifier != nil {
+		if err := clients.FlushNotifier(ctx, notifier); err != nil {
+			logger.Log.Errorf("❌ Ошибка отправки дайджестов: %v", err)
+		}
+	}
+	return report, ctx.Err()
+}
+
+// backfillItems собирает проекты из диапазона в виде элементов RSS, чтобы обработать их тем же конвейером.
+// Если достигнут предел страниц, вместе с проектами возвращается clients.ErrActsTruncated
+func backfillItems(ctx context.Context, r BackfillRange) ([]dto.RSSItem, error) {
+	var items []dto.RSSItem
+	q := clients.ActsQuery{Filter: r.filter(), PageSize: backfillPageSize, MaxPages: config.GetBackfillMaxPages()}
+	for p, err := range clients.ListActs(ctx, q) {
+		if err != nil {
+			return items, err
+		}
+		created := p.CreationDate.Time
+		var desc strings.Builder
+		fmt.Fprintf(&desc, "ID проекта: %s\n", p.ProjectID)
+		if !created.IsZero() {
+			fmt.Fprintf(&desc, "Дата создания: %s\n", created.Format("02.01.2006"))
+		}
+		fmt.Fprintf(&desc, "Разработчик: %s\n", p.DevelopedDepartment.Description)
+		fmt.Fprintf(&desc, "Процедура: %s", p.Procedure.Description)
+		if len(p.Okveds) > 0 {
+			codes := make([]string, 0, len(p.Okveds))
+			for _, o := range p.Okveds {
+				codes = append(codes, o.Code())
+			}
+			fmt.Fprintf(&desc, "\nОКВЭД: %s", strings.Join(codes, ", "))
+		}
+		item := dto.RSSItem{
+			Title:       p.Title,
+			Link:        p.ProjectURL(),
+			Description: desc.String(),
+		}
+		item.ParseInfo()
+		items = append(items, item)
+		if len(items)%backfillPageSize == 0 {
+			logger.Log.Infof("Проектов в диапазоне: %d", len(items))
+		}
+	}
+	return items, nil
+}
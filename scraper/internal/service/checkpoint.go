@@ -0,0 +1,170 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+)
+
+// scanTracker ведет контрольную точку сканирования: состояние каждого элемента RSS сохраняется
+// в data/scan_checkpoint.json после каждого шага, а полностью обработанный элемент сразу
+// дописывается в rss.json. Если процесс упадет, следующий запуск продолжит ровно те элементы
+// и файлы, которые не были завершены
+type scanTracker struct {
+	mu sync.Mutex
+	cp *repository.ScanCheckpoint
+	// processed - снимок RSS, в котором только обработанные элементы
+	processed dto.RSS
+}
+
+// newScanTracker объединяет незавершенную работу прошлого сканирования с новыми элементами RSS
+// и возвращает трекер и элементы для обработки (сначала продолжаемые, затем новые)
+func newScanTracker(feed, oldFeed *dto.RSS, newItems []dto.RSSItem) (*scanTracker, []dto.RSSItem) {
+	cp, err := repository.LoadCheckpoint()
+	if err != nil {
+		logger.Log.Warnf("Не удалось загрузить контрольную точку сканирования: %v", err)
+	}
+	if cp == nil {
+		cp = &repository.ScanCheckpoint{Items: map[string]*repository.ItemCheckpoint{}}
+	}
+
+	t := &scanTracker{cp: cp}
+	t.processed.Channel = feed.Channel
+	t.processed.Channel.Items = nil
+	if oldFeed != nil {
+		t.processed.Channel.Items = append(t.processed.Channel.Items, oldFeed.Channel.Items...)
+	}
+
+	var items []dto.RSSItem
+	var order []string
+	for _, link := range cp.Order {
+		if ic, ok := cp.Items[link]; ok {
+			items = append(items, ic.Item)
+			order = append(order, link)
+		}
+	}
+	if len(items) > 0 {
+		logger.Log.Infof("♻️ Продолжаем прерванное сканирование от %s: незавершенных элементов %d",
+			cp.StartedAt.Format("02.01.2006 15:04"), len(items))
+	}
+
+	for _, it := range newItems {
+		if _, ok := cp.Items[it.Link]; ok {
+			continue
+		}
+		cp.Items[it.Link] = &repository.ItemCheckpoint{Item: it}
+		items = append(items, it)
+		order = append(order, it.Link)
+	}
+	cp.Order = order
+	if cp.StartedAt.IsZero() {
+		cp.StartedAt = time.Now()
+	}
+	if cp.ScanID == "" {
+		cp.ScanID = repository.NewScanID("scan")
+	}
+	t.save()
+	return t, items
+}
+
+// scanID возвращает идентификатор сканирования для журнала совпадений
+func (t *scanTracker) scanID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cp.ScanID
+}
+
+// state возвращает копию сохраненного состояния элемента
+func (t *scanTracker) state(link string) repository.ItemCheckpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ic, ok := t.cp.Items[link]
+	if !ok {
+		return repository.ItemCheckpoint{}
+	}
+	copied := *ic
+	copied.FilesDone = make(map[string]bool, len(ic.FilesDone))
+	for f, done := range ic.FilesDone {
+		copied.FilesDone[f] = done
+	}
+	return copied
+}
+
+// pageDone отмечает, что страница проекта проверена
+func (t *scanTracker) pageDone(link string) {
+	t.update(link, func(ic *repository.ItemCheckpoint) { ic.PageDone = true })
+}
+
+// filesFound запоминает список файлов проекта
+func (t *scanTracker) filesFound(link string, files []dto.StageFile) {
+	t.update(link, func(ic *repository.ItemCheckpoint) {
+		ic.FilesKnown = true
+		ic.Files = make([]string, 0, len(files))
+		for _, f := range files {
+			ic.Files = append(ic.Files, f.URL)
+		}
+		ic.FileInfo = files
+	})
+}
+
+// fileDone отмечает, что файл проверен и уведомление о нем отправлено
+func (t *scanTracker) fileDone(link, fileURL string) {
+	t.update(link, func(ic *repository.ItemCheckpoint) {
+		if ic.FilesDone == nil {
+			ic.FilesDone = map[string]bool{}
+		}
+		ic.FilesDone[fileURL] = true
+	})
+}
+
+// update меняет состояние элемента и сохраняет контрольную точку; обработанный элемент
+// переносится в rss.json
+func (t *scanTracker) update(link string, fn func(ic *repository.ItemCheckpoint)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ic, ok := t.cp.Items[link]
+	if !ok {
+		return
+	}
+	fn(ic)
+
+	if ic.Completed() {
+		delete(t.cp.Items, link)
+		t.processed.Channel.Items = append(t.processed.Channel.Items, ic.Item)
+		if err := repository.SaveRSS(&t.processed); err != nil {
+			logger.Log.Errorf("❌ Не удалось сохранить RSS: %v", err)
+		}
+		logger.Log.Debugf("Элемент RSS обработан: %s", link)
+	}
+	t.save()
+}
+
+// save сохраняет контрольную точку; вызывается под t.mu
+func (t *scanTracker) save() {
+	if err := repository.SaveCheckpoint(t.cp); err != nil {
+		logger.Log.Errorf("❌ Не удалось сохранить контрольную точку сканирования: %v", err)
+	}
+}
+
+// finish завершает сканирование: если все элементы обработаны, сохраняет актуальный RSS
+// и удаляет контрольную точку. Иначе незавершенная работа остается для следующего запуска
+func (t *scanTracker) finish(feed *dto.RSS) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.cp.Items) > 0 {
+		logger.Log.Infof("💾 Незавершенных элементов RSS: %d, они будут продолжены при следующем сканировании", len(t.cp.Items))
+		return
+	}
+	if err := repository.SaveRSS(feed); err != nil {
+		logger.Log.Errorf("❌ Не удалось сохранить RSS: %v", err)
+	}
+	if err := repository.ClearCheckpoint(); err != nil {
+		logger.Log.Warnf("Не удалось удалить контрольную точку сканирования: %v", err)
+	}
+	logger.Log.Info("RSS сохранен для следующего сравнения")
+}
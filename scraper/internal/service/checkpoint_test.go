@@ -0,0 +1,166 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+)
+
+func testFeed(links ...string) *dto.RSS {
+	feed := &dto.RSS{Channel: dto.RSSChannel{Title: "regulation.gov.ru"}}
+	for _, link := range links {
+		feed.Channel.Items = append(feed.Channel.Items, dto.RSSItem{Title: "Проект " + link, Link: link})
+	}
+	return feed
+}
+
+func itemLinks(items []dto.RSSItem) []string {
+	links := make([]string, 0, len(items))
+	for _, it := range items {
+		links = append(links, it.Link)
+	}
+	return links
+}
+
+// startScan открывает сканирование так же, как scanner_parallel: новые элементы ленты
+// относительно сохраненного rss.json плюс незавершенные из контрольной точки
+func startScan(t *testing.T, feed *dto.RSS) (*scanTracker, []dto.RSSItem) {
+	t.Helper()
+	oldFeed, err := repository.LoadPreviousRSS()
+	if err != nil {
+		t.Fatalf("LoadPreviousRSS: %v", err)
+	}
+	return newScanTracker(feed, oldFeed, repository.GetNewRSSItems(feed, oldFeed))
+}
+
+func TestScanTrackerResumesPartialRun(t *testing.T) {
+	files := []dto.StageFile{{URL: "https://regulation.gov.ru/GetFile/1"}, {URL: "https://regulation.gov.ru/GetFile/2"}}
+	// completeItem проходит все шаги элемента без файлов
+	completeItem := func(tr *scanTracker, link string) {
+		tr.pageDone(link)
+		tr.filesFound(link, nil)
+	}
+
+	tests := []struct {
+		name string
+		// run - шаги первого запуска, после которых он прерывается
+		run       func(tr *scanTracker)
+		nextFeed  *dto.RSS
+		wantItems []string
+		// wantSaved - элементы, попавшие в rss.json после первого запуска
+		wantSaved  []string
+		checkpoint bool
+	}{
+		{
+			name: "все элементы обработаны",
+			run: func(tr *scanTracker) {
+				for _, link := range []string{"a", "b", "c"} {
+					completeItem(tr, link)
+				}
+			},
+			nextFeed:   testFeed("a", "b", "c"),
+			wantItems:  []string{},
+			wantSaved:  []string{"a", "b", "c"},
+			checkpoint: false,
+		},
+		{
+			name: "прерван на середине",
+			run: func(tr *scanTracker) {
+				completeItem(tr, "a")
+				tr.pageDone("b")
+				tr.filesFound("b", files)
+				tr.fileDone("b", files[0].URL)
+			},
+			nextFeed:   testFeed("a", "b", "c"),
+			wantItems:  []string{"b", "c"},
+			wantSaved:  []string{"a"},
+			checkpoint: true,
+		},
+		{
+			name: "незавершенные элементы идут раньше новых",
+			run: func(tr *scanTracker) {
+				completeItem(tr, "b")
+			},
+			nextFeed:   testFeed("d", "a", "b", "c"),
+			wantItems:  []string{"a", "c", "d"},
+			wantSaved:  []string{"b"},
+			checkpoint: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PROJECT_ROOT", t.TempDir())
+
+			feed := testFeed("a", "b", "c")
+			tr, items := startScan(t, feed)
+			if got := itemLinks(items); !slices.Equal(got, []string{"a", "b", "c"}) {
+				t.Fatalf("первый запуск: элементы %v", got)
+			}
+			scanID := tr.scanID()
+			tt.run(tr)
+			tr.finish(feed)
+
+			saved, err := repository.LoadPreviousRSS()
+			if err != nil || saved == nil {
+				t.Fatalf("rss.json не сохранен: %v", err)
+			}
+			if got := itemLinks(saved.Channel.Items); !slices.Equal(got, tt.wantSaved) {
+				t.Errorf("в rss.json %v, ожидалось %v", got, tt.wantSaved)
+			}
+			cp, err := repository.LoadCheckpoint()
+			if err != nil {
+				t.Fatalf("LoadCheckpoint: %v", err)
+			}
+			if (cp != nil) != tt.checkpoint {
+				t.Fatalf("контрольная точка сохранена: %v, ожидалось %v", cp != nil, tt.checkpoint)
+			}
+
+			next, items := startScan(t, tt.nextFeed)
+			if got := itemLinks(items); !slices.Equal(got, tt.wantItems) {
+				t.Errorf("второй запуск: элементы %v, ожидалось %v", got, tt.wantItems)
+			}
+			if tt.checkpoint && next.scanID() != scanID {
+				t.Errorf("продолженное сканирование сменило ID: %s, было %s", next.scanID(), scanID)
+			}
+		})
+	}
+}
+
+func TestScanTrackerKeepsFileProgress(t *testing.T) {
+	t.Setenv("PROJECT_ROOT", t.TempDir())
+	files := []dto.StageFile{{URL: "https://regulation.gov.ru/GetFile/1", Name: "проект.docx"}, {URL: "https://regulation.gov.ru/GetFile/2"}}
+
+	feed := testFeed("a")
+	tr, _ := startScan(t, feed)
+	tr.pageDone("a")
+	tr.filesFound("a", files)
+	tr.fileDone("a", files[0].URL)
+	tr.finish(feed)
+
+	next, items := startScan(t, feed)
+	if got := itemLinks(items); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("элементы %v, ожидалось [a]", got)
+	}
+	st := next.state("a")
+	if !st.PageDone || !st.FilesKnown {
+		t.Errorf("состояние не восстановлено: PageDone %v, FilesKnown %v", st.PageDone, st.FilesKnown)
+	}
+	if !st.FilesDone[files[0].URL] || st.FilesDone[files[1].URL] {
+		t.Errorf("проверенные файлы: %v", st.FilesDone)
+	}
+	if got := st.StageFiles(); len(got) != 2 || got[0].Name != "проект.docx" {
+		t.Errorf("описания файлов: %+v", got)
+	}
+
+	// Оставшийся файл завершает элемент: он попадает в rss.json, контрольная точка удаляется
+	next.fileDone("a", files[1].URL)
+	next.finish(feed)
+	if cp, _ := repository.LoadCheckpoint(); cp != nil {
+		t.Errorf("контрольная точка не удалена: %+v", cp)
+	}
+	if _, items := startScan(t, feed); len(items) != 0 {
+		t.Errorf("после завершения осталось элементов: %d", len(items))
+	}
+}
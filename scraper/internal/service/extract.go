@@ -0,0 +1,377 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/notenoughtea/law_scraper/internal/matcher"
+)
+
+// Потоковое извлечение текста: документ читается частями, текст переводится в нижний регистр
+// и сразу передается автомату поиска, поэтому полный текст файла в памяти не собирается
+
+// charsetProbeSize - сколько первых байт текстового файла проверяем, чтобы выбрать между UTF-8 и cp1251
+const charsetProbeSize = 64 << 10
+
+// streamText пишет текст файла в нижнем регистре в w: для DOCX - текст word/document.xml,
+// для остальных файлов - содержимое как текст UTF-8 или cp1251
+func streamText(body *fileBody, w io.Writer) error {
+	if doc := docxDocument(body); doc != nil {
+		return streamDocxText(doc, w)
+	}
+	return streamPlainText(body.reader(), w)
+}
+
+// isTextDocument сообщает, что из файла извлекается настоящий текст: это DOCX или файл,
+// начало которого похоже на текст. PDF, сканы, архивы и прочие двоичные файлы тоже
+// проверяются на ключевые слова, но в хранилище текстов и в индекс не попадают
+func isTextDocument(body *fileBody) bool {
+	return docxDocument(body) != nil || looksLikeText(body.head(charsetProbeSize))
+}
+
+// docxDocument возвращает word/document.xml, если файл - DOCX, иначе nil
+func docxDocument(body *fileBody) *zip.File {
+	if !bytes.HasPrefix(body.head(4), []byte("PK\x03\x04")) {
+		return nil
+	}
+	zr, err := zip.NewReader(body.readerAt(), body.size)
+	if err != nil {
+		return nil
+	}
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			return f
+		}
+	}
+	return nil
+}
+
+// maxControlBytesPercent - доля управляющих символов, при которой начало файла еще считается текстом
+const maxControlBytesPercent = 1
+
+// looksLikeText проверяет начало файла: в тексте (UTF-8 или cp1251) нет нулевых байт
+// и почти нет управляющих символов, кроме переводов строк и табуляции
+func looksLikeText(head []byte) bool {
+	if len(head) == 0 || bytes.HasPrefix(head, []byte("%PDF-")) {
+		return false
+	}
+	control := 0
+	for _, b := range head {
+		switch {
+		case b == 0:
+			return false
+		case b == '\t' || b == '\n' || b == '\r' || b == '\f':
+		case b < 0x20 || b == 0x7f:
+			control++
+		}
+	}
+	return control*100 <= len(head)*maxControlBytesPercent
+}
+
+// streamDocxText разбирает word/document.xml потоком
+func streamDocxText(doc *zip.File, w io.Writer) error {
+	rc, err := doc.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	lw := &lowerWriter{w: w}
+	dec := xml.NewDecoder(rc)
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "t" { // w:t
+				inText = true
+			}
+		case xml.EndElement:
+			if t.Name.Local == "t" {
+				inText = false
+				lw.Write([]byte{' '})
+			}
+		case xml.CharData:
+			if inText {
+				lw.Write(t)
+			}
+		}
+	}
+	return lw.Flush()
+}
+
+// streamPlainText декодирует текстовый файл. Кодировка выбирается по началу файла:
+// если первые charsetProbeSize байт - корректный UTF-8, файл читается как UTF-8, иначе как cp1251
+func streamPlainText(r io.Reader, w io.Writer) error {
+	br := bufio.NewReaderSize(r, charsetProbeSize)
+	head, err := br.Peek(charsetProbeSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+
+	if validUTF8Prefix(head, len(head) < charsetProbeSize) {
+		lw := &lowerWriter{w: w}
+		if _, err := io.Copy(lw, br); err != nil {
+			return err
+		}
+		return lw.Flush()
+	}
+	_, err = io.Copy(cp1251Writer{w: w}, br)
+	return err
+}
+
+// validUTF8Prefix проверяет, что b - корректный UTF-8; если файл не закончился (complete = false),
+// обрезанный последний символ ошибкой не считается
+func validUTF8Prefix(b []byte, complete bool) bool {
+	if !complete {
+		for i := 0; i < utf8.UTFMax-1 && i < len(b); i++ {
+			if utf8.RuneStart(b[len(b)-1-i]) {
+				if !utf8.FullRune(b[len(b)-1-i:]) {
+					b = b[:len(b)-1-i]
+				}
+				break
+			}
+		}
+	}
+	return utf8.Valid(b)
+}
+
+// lowerWriter переводит текст UTF-8 в нижний регистр; символ, разрезанный между вызовами Write,
+// дожидается продолжения
+type lowerWriter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (l *lowerWriter) Write(p []byte) (int, error) {
+	data := p
+	if len(l.pending) > 0 {
+		data = append(l.pending, p...)
+		l.pending = nil
+	}
+	cut := len(data)
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				cut = len(data) - i
+			}
+			break
+		}
+	}
+	if cut < len(data) {
+		l.pending = append([]byte(nil), data[cut:]...)
+	}
+	if _, err := io.WriteString(l.w, strings.ToLower(string(data[:cut]))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush дописывает остаток, если файл закончился посреди символа
+func (l *lowerWriter) Flush() error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(l.w, strings.ToLower(string(l.pending)))
+	l.pending = nil
+	return err
+}
+
+// cp1251Table - таблица соответствий 0x80-0xFF → Unicode (cp1251)
+var cp1251Table = [128]rune{
+	0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+	0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x00, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+	0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7,
+	0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+	0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7,
+	0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+	0x0410, 0x0411, 0x0412, 0x0413, 0x0414, 0x0415, 0x0416, 0x0417,
+	0x0418, 0x0419, 0x041A, 0x041B, 0x041C, 0x041D, 0x041E, 0x041F,
+	0x0420, 0x0421, 0x0422, 0x0423, 0x0424, 0x0425, 0x0426, 0x0427,
+	0x0428, 0x0429, 0x042A, 0x042B, 0x042C, 0x042D, 0x042E, 0x042F,
+	0x0430, 0x0431, 0x0432, 0x0433, 0x0434, 0x0435, 0x0436, 0x0437,
+	0x0438, 0x0439, 0x043A, 0x043B, 0x043C, 0x043D, 0x043E, 0x043F,
+	0x0440, 0x0441, 0x0442, 0x0443, 0x0444, 0x0445, 0x0446, 0x0447,
+	0x0448, 0x0449, 0x044A, 0x044B, 0x044C, 0x044D, 0x044E, 0x044F,
+}
+
+// decodeWindows1251 декодирует байты cp1251 в строку UTF-8
+func decodeWindows1251(b []byte) string {
+	out := make([]rune, 0, len(b))
+	for _, by := range b {
+		if by < 0x80 {
+			out = append(out, rune(by))
+		} else {
+			r := cp1251Table[by-0x80]
+			if r == 0x00 {
+				// неизвестный символ – пропустим
+				continue
+			}
+			out = append(out, r)
+		}
+	}
+	return strings.ToLower(string(out))
+}
+
+// cp1251Writer декодирует cp1251 в UTF-8 нижнего регистра (каждый байт - отдельный символ)
+type cp1251Writer struct {
+	w io.Writer
+}
+
+func (c cp1251Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(c.w, decodeWindows1251(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// snippetWindowBytes - сколько байт текста до совпадения и после него нужно для фрагмента
+// (на байт больше радиуса, чтобы snippetAround понимал, что текст продолжается)
+const snippetWindowBytes = snippetRadius*utf8.UTFMax + 1
+
+// textScanner принимает текст в нижнем регистре, ищет ключевые слова автоматом Ахо–Корасик
+// и держит скользящее окно последних байт текста, из которого вырезаются фрагменты
+// вокруг первых вхождений. Память не зависит от размера документа
+type textScanner struct {
+	stream   *matcher.Stream
+	keywords []string
+	found    []bool
+	// hits - число всех вхождений ключевых слов
+	hits int
+
+	// window - последние байты текста перед текущей частью, windowStart - смещение window[0]
+	window      []byte
+	windowStart int64
+	windowSize  int
+	// chunk - часть текста, которую сейчас обрабатывает автомат
+	chunk      []byte
+	chunkStart int64
+
+	drafts   []*snippetDraft
+	snippets []string
+}
+
+// snippetDraft - фрагмент, которому еще не хватает текста после совпадения
+type snippetDraft struct {
+	text           []byte
+	kwStart, kwLen int
+	// next - смещение следующего нужного байта, need - сколько байт осталось дочитать
+	next int64
+	need int
+}
+
+func newTextScanner(m *matcher.Matcher) *textScanner {
+	keywords := m.Keywords()
+	t := &textScanner{keywords: keywords, found: make([]bool, len(keywords)), windowSize: snippetWindowBytes}
+	longest := 0
+	for _, kw := range keywords {
+		longest = max(longest, len(kw))
+	}
+	t.windowSize += longest
+	t.stream = m.NewStream(t.onHit)
+	return t
+}
+
+func (t *textScanner) Write(p []byte) (int, error) {
+	t.chunk, t.chunkStart = p, t.stream.Offset()
+	t.stream.Write(p)
+	t.feedDrafts()
+
+	// Сдвигаем окно: оставляем только последние windowSize байт
+	if len(p) >= t.windowSize {
+		t.window = append(t.window[:0], p[len(p)-t.windowSize:]...)
+	} else {
+		t.window = append(t.window, p...)
+		if extra := len(t.window) - t.windowSize; extra > 0 {
+			t.window = append(t.window[:0], t.window[extra:]...)
+		}
+	}
+	t.windowStart = t.stream.Offset() - int64(len(t.window))
+	t.chunk = nil
+	return len(p), nil
+}
+
+// onHit отмечает найденное слово и начинает фрагмент вокруг его первого вхождения
+func (t *textScanner) onHit(h matcher.Hit) {
+	t.hits++
+	if t.found[h.Keyword] {
+		return
+	}
+	t.found[h.Keyword] = true
+	if len(t.snippets)+len(t.drafts) >= maxSnippets {
+		return
+	}
+	from := max(h.Start-snippetWindowBytes, t.windowStart, 0)
+	d := &snippetDraft{
+		text:    t.slice(from, h.End),
+		kwStart: int(h.Start - from),
+		kwLen:   int(h.End - h.Start),
+		next:    h.End,
+		need:    snippetWindowBytes,
+	}
+	t.drafts = append(t.drafts, d)
+}
+
+// slice копирует текст [from, to) из окна и текущей части
+func (t *textScanner) slice(from, to int64) []byte {
+	out := make([]byte, 0, to-from)
+	if from < t.chunkStart {
+		out = append(out, t.window[from-t.windowStart:min(to, t.chunkStart)-t.windowStart]...)
+		from = t.chunkStart
+	}
+	if to > from {
+		out = append(out, t.chunk[from-t.chunkStart:to-t.chunkStart]...)
+	}
+	return out
+}
+
+// feedDrafts дописывает во фрагменты текст после совпадения из текущей части
+func (t *textScanner) feedDrafts() {
+	chunkEnd := t.chunkStart + int64(len(t.chunk))
+	kept := t.drafts[:0]
+	for _, d := range t.drafts {
+		if d.next < chunkEnd {
+			take := min(int64(d.need), chunkEnd-d.next)
+			d.text = append(d.text, t.chunk[d.next-t.chunkStart:d.next-t.chunkStart+take]...)
+			d.next += take
+			d.need -= int(take)
+		}
+		if d.need == 0 {
+			t.snippets = append(t.snippets, d.finish())
+			continue
+		}
+		kept = append(kept, d)
+	}
+	t.drafts = kept
+}
+
+func (d *snippetDraft) finish() string {
+	return snippetAround(string(d.text), d.kwStart, d.kwLen)
+}
+
+// result возвращает найденные ключевые слова (в порядке списка) и фрагменты текста
+func (t *textScanner) result() (found []string, snippets []string) {
+	for _, d := range t.drafts {
+		t.snippets = append(t.snippets, d.finish())
+	}
+	t.drafts = nil
+	for i, ok := range t.found {
+		if ok {
+			found = append(found, t.keywords[i])
+		}
+	}
+	return found, t.snippets
+}
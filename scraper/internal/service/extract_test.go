@@ -0,0 +1,130 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/notenoughtea/law_scraper/internal/matcher"
+)
+
+// scanChunks прогоняет текст через textScanner частями по size байт
+func scanChunks(m *matcher.Matcher, text []byte, size int) (found, snippets []string) {
+	t := newTextScanner(m)
+	for len(text) > 0 {
+		n := min(size, len(text))
+		t.Write(text[:n])
+		text = text[n:]
+	}
+	return t.result()
+}
+
+func TestTextScannerSnippetsAcrossChunks(t *testing.T) {
+	filler := strings.Repeat("положения настоящего порядка применяются ", 20)
+	tests := []struct {
+		name     string
+		keywords []string
+		text     string
+		found    []string
+		// contains - что должно попасть в фрагменты
+		contains []string
+		snippets int
+	}{
+		{
+			name:     "короткий текст без многоточий",
+			keywords: []string{"тариф"},
+			text:     "установить тариф на перевозку",
+			found:    []string{"тариф"},
+			contains: []string{"установить тариф на перевозку"},
+			snippets: 1,
+		},
+		{
+			name:     "слово в середине длинного текста",
+			keywords: []string{"маркировка"},
+			text:     filler + "обязательная маркировка товаров " + filler,
+			found:    []string{"маркировка"},
+			contains: []string{"обязательная маркировка товаров"},
+			snippets: 1,
+		},
+		{
+			name:     "слово в начале и в конце",
+			keywords: []string{"налог", "сбор"},
+			text:     "налог " + filler + " сбор",
+			found:    []string{"налог", "сбор"},
+			contains: []string{"налог положения", "применяются сбор"},
+			snippets: 2,
+		},
+		{
+			name:     "фрагмент только для первого вхождения",
+			keywords: []string{"лицензия"},
+			text:     "лицензия " + filler + " лицензия",
+			found:    []string{"лицензия"},
+			contains: []string{"лицензия положения"},
+			snippets: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := matcher.New(tt.keywords)
+			text := []byte(tt.text)
+			wantFound, wantSnippets := scanChunks(m, text, len(text))
+
+			if !slices.Equal(wantFound, tt.found) {
+				t.Errorf("найдено %v, ожидалось %v", wantFound, tt.found)
+			}
+			joined := strings.Join(wantSnippets, "\n")
+			for _, s := range tt.contains {
+				if !strings.Contains(joined, s) {
+					t.Errorf("во фрагментах нет %q:\n%s", s, joined)
+				}
+			}
+			if len(wantSnippets) != tt.snippets {
+				t.Errorf("фрагментов %d, ожидалось %d", len(wantSnippets), tt.snippets)
+			}
+
+			// Разбиение текста на части, в том числе посреди символов и ключевых слов,
+			// не меняет ни найденные слова, ни фрагменты
+			for _, size := range []int{1, 3, 7, 64, 333, snippetWindowBytes} {
+				found, snippets := scanChunks(m, text, size)
+				if !slices.Equal(found, wantFound) || !slices.Equal(snippets, wantSnippets) {
+					t.Errorf("части по %d байт: %v %q, ожидалось %v %q", size, found, snippets, wantFound, wantSnippets)
+				}
+			}
+		})
+	}
+}
+
+func TestSnippetAround(t *testing.T) {
+	long := strings.Repeat("а", 200)
+	tests := []struct {
+		name   string
+		text   string
+		kw     string
+		want   string
+		prefix bool
+		suffix bool
+	}{
+		{"весь текст помещается", "один  два\nтри", "два", "один два три", false, false},
+		{"обрезка слева", long + " ключ", "ключ", "", true, false},
+		{"обрезка справа", "ключ " + long, "ключ", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := strings.Index(tt.text, tt.kw)
+			got := snippetAround(tt.text, start, len(tt.kw))
+			if tt.want != "" && got != tt.want {
+				t.Errorf("snippetAround = %q, ожидалось %q", got, tt.want)
+			}
+			if strings.HasPrefix(got, "…") != tt.prefix || strings.HasSuffix(got, "…") != tt.suffix {
+				t.Errorf("многоточия в %q: слева %v, справа %v", got, tt.prefix, tt.suffix)
+			}
+			if !strings.Contains(got, tt.kw) {
+				t.Errorf("в %q нет ключевого слова", got)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("фрагмент разрезал символ: %q", got)
+			}
+		})
+	}
+}
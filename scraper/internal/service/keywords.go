@@ -0,0 +1,21 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/matcher"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+)
+
+// compileKeywords загружает ключевые слова в нижнем регистре и строит по ним автомат поиска.
+// Автомат строится один раз на сканирование, и им пользуются все места поиска:
+// страницы проектов, файлы и потоковое извлечение текста
+func compileKeywords() *matcher.Matcher {
+	keywords := repository.LoadKeywords()
+	for i := range keywords {
+		keywords[i] = strings.ToLower(keywords[i])
+	}
+	logger.Log.Infof("Ищем ключевые слова: %v", keywords)
+	return matcher.New(keywords)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/repository"
+)
+
+// KeywordStat - сколько совпадений за период содержат ключевое слово
+type KeywordStat struct {
+	Keyword string `json:"keyword"`
+	Matches int    `json:"matches"`
+}
+
+// MatchReport - сводка журнала совпадений за период
+type MatchReport struct {
+	Since    time.Time `json:"since"`
+	Scans    int       `json:"scans"`
+	Matches  int       `json:"matches"`
+	Files    int       `json:"files"`
+	Projects int       `json:"projects"`
+	// Undelivered - совпадения, уведомления о которых не были доставлены
+	Undelivered int           `json:"undelivered"`
+	Keywords    []KeywordStat `json:"keywords"`
+	// Top - совпадения во вложениях с наибольшим числом вхождений ключевых слов
+	Top []repository.MatchRecord `json:"top"`
+}
+
+// BuildMatchReport собирает сводку по записям журнала совпадений, найденным не раньше since
+func BuildMatchReport(since time.Time, top int) (*MatchReport, error) {
+	report := &MatchReport{Since: since}
+	scans := map[string]bool{}
+	projects := map[string]bool{}
+	keywords := map[string]int{}
+	for rec, err := range repository.ReadMatches(repository.MatchQuery{Since: since}) {
+		if err != nil {
+			return report, err
+		}
+		report.Matches++
+		scans[rec.ScanID] = true
+		projects[rec.ProjectURL] = true
+		if rec.NotifiedAt.IsZero() {
+			report.Undelivered++
+		}
+		for _, kw := range rec.Keywords {
+			keywords[kw]++
+		}
+		if !rec.IsFile() {
+			continue
+		}
+		report.Files++
+		if top > 0 {
+			report.Top = append(report.Top, rec)
+			sort.SliceStable(report.Top, func(i, j int) bool { return report.Top[i].Score > report.Top[j].Score })
+			if len(report.Top) > top {
+				report.Top = report.Top[:top]
+			}
+		}
+	}
+	report.Scans, report.Projects = len(scans), len(projects)
+
+	for kw, n := range keywords {
+		report.Keywords = append(report.Keywords, KeywordStat{Keyword: kw, Matches: n})
+	}
+	sort.Slice(report.Keywords, func(i, j int) bool {
+		if report.Keywords[i].Matches != report.Keywords[j].Matches {
+			return report.Keywords[i].Matches > report.Keywords[j].Matches
+		}
+		return report.Keywords[i].Keyword < report.Keywords[j].Keyword
+	})
+	return report, nil
+}
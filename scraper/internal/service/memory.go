@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+)
+
+// memoryBudget - семафор, размер которого задан в байтах. Воркеры загрузки резервируют
+// из него размер файла до скачивания и освобождают после поиска, поэтому сумма файлов
+// в памяти не превышает бюджета, сколько бы воркеров ни работало
+type memoryBudget struct {
+	mu       sync.Mutex
+	capacity int64
+	used     int64
+	// waiters - ожидающие резервирования в порядке очереди
+	waiters []*memoryWaiter
+}
+
+type memoryWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+var (
+	scanMemory     *memoryBudget
+	scanMemoryOnce sync.Once
+)
+
+// getScanMemory возвращает общий для всех сканирований процесса бюджет памяти (SCAN_MEMORY_MB)
+func getScanMemory() *memoryBudget {
+	scanMemoryOnce.Do(func() {
+		scanMemory = &memoryBudget{capacity: config.GetScanMemoryBytes()}
+	})
+	return scanMemory
+}
+
+// acquire резервирует n байт, дожидаясь, пока их освободят другие воркеры.
+// Запрос больше всего бюджета урезается до бюджета
+func (b *memoryBudget) acquire(ctx context.Context, n int64) (int64, error) {
+	if n > b.capacity {
+		n = b.capacity
+	}
+	b.mu.Lock()
+	if len(b.waiters) == 0 && b.used+n <= b.capacity {
+		b.used += n
+		b.mu.Unlock()
+		return n, nil
+	}
+	w := &memoryWaiter{n: n, ready: make(chan struct{})}
+	b.waiters = append(b.waiters, w)
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return n, nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Резерв успели выдать - возвращаем его
+			b.used -= n
+			b.wakeLocked()
+		default:
+			for i, other := range b.waiters {
+				if other == w {
+					b.waiters = append(b.waiters[:i], b.waiters[i+1:]...)
+					break
+				}
+			}
+			b.wakeLocked()
+		}
+		return 0, ctx.Err()
+	}
+}
+
+// release возвращает n байт в бюджет
+func (b *memoryBudget) release(n int64) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.wakeLocked()
+	b.mu.Unlock()
+}
+
+// wakeLocked выдает резерв ожидающим по очереди, пока хватает места; вызывается под b.mu
+func (b *memoryBudget) wakeLocked() {
+	for len(b.waiters) > 0 {
+		w := b.waiters[0]
+		if b.used+w.n > b.capacity {
+			return
+		}
+		b.used += w.n
+		b.waiters = b.waiters[1:]
+		close(w.ready)
+	}
+}
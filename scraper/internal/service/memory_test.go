@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// acquireAsync резервирует n байт в отдельной горутине; результат приходит в канал
+func acquireAsync(ctx context.Context, b *memoryBudget, n int64) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.acquire(ctx, n)
+		done <- err
+	}()
+	return done
+}
+
+// waitQueued ждет, пока в очереди бюджета окажется want ожидающих
+func waitQueued(t *testing.T, b *memoryBudget, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		b.mu.Lock()
+		n := len(b.waiters)
+		b.mu.Unlock()
+		if n == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("в очереди %d ожидающих, ожидалось %d", n, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func assertPending(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		t.Fatalf("резерв выдан раньше времени (err = %v)", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func assertGranted(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("резерв не выдан")
+	}
+}
+
+func TestMemoryBudgetAcquireRelease(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int64
+		requests []int64
+		// granted - сколько выдано на каждый запрос, used - занято после всех запросов
+		granted []int64
+		used    int64
+	}{
+		{"в пределах бюджета", 100, []int64{30, 70}, []int64{30, 70}, 100},
+		{"запрос больше бюджета урезается", 100, []int64{250}, []int64{100}, 100},
+		{"нулевой запрос", 100, []int64{0, 100}, []int64{0, 100}, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &memoryBudget{capacity: tt.capacity}
+			for i, n := range tt.requests {
+				got, err := b.acquire(context.Background(), n)
+				if err != nil {
+					t.Fatalf("acquire(%d): %v", n, err)
+				}
+				if got != tt.granted[i] {
+					t.Errorf("acquire(%d) = %d, ожидалось %d", n, got, tt.granted[i])
+				}
+			}
+			if b.used != tt.used {
+				t.Errorf("занято %d, ожидалось %d", b.used, tt.used)
+			}
+			for _, n := range tt.granted {
+				b.release(n)
+			}
+			if b.used != 0 {
+				t.Errorf("после release занято %d", b.used)
+			}
+		})
+	}
+}
+
+func TestMemoryBudgetWaitsForRelease(t *testing.T) {
+	b := &memoryBudget{capacity: 100}
+	if _, err := b.acquire(context.Background(), 60); err != nil {
+		t.Fatal(err)
+	}
+
+	done := acquireAsync(context.Background(), b, 60)
+	waitQueued(t, b, 1)
+	assertPending(t, done)
+
+	b.release(60)
+	assertGranted(t, done)
+	if b.used != 60 {
+		t.Errorf("занято %d, ожидалось 60", b.used)
+	}
+}
+
+func TestMemoryBudgetServesWaitersInOrder(t *testing.T) {
+	b := &memoryBudget{capacity: 100}
+	if _, err := b.acquire(context.Background(), 80); err != nil {
+		t.Fatal(err)
+	}
+
+	// Маленький запрос помещается, но встает в очередь за большим
+	big := acquireAsync(context.Background(), b, 50)
+	waitQueued(t, b, 1)
+	small := acquireAsync(context.Background(), b, 20)
+	waitQueued(t, b, 2)
+	assertPending(t, small)
+
+	b.release(80)
+	assertGranted(t, big)
+	assertGranted(t, small)
+	if b.used != 70 {
+		t.Errorf("занято %d, ожидалось 70", b.used)
+	}
+}
+
+func TestMemoryBudgetCancelledWaiterLeavesQueue(t *testing.T) {
+	b := &memoryBudget{capacity: 100}
+	if _, err := b.acquire(context.Background(), 80); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	big := acquireAsync(ctx, b, 50)
+	waitQueued(t, b, 1)
+	small := acquireAsync(context.Background(), b, 20)
+	waitQueued(t, b, 2)
+
+	// Отмененный запрос уходит из очереди и пропускает следующий
+	cancel()
+	if err := <-big; !errors.Is(err, context.Canceled) {
+		t.Fatalf("acquire после отмены: %v", err)
+	}
+	assertGranted(t, small)
+	if b.used != 100 {
+		t.Errorf("занято %d, ожидалось 100", b.used)
+	}
+}
@@ -1,50 +1,50 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
+	"unicode/utf8"
 
 	"github.com/notenoughtea/law_scraper/internal/clients"
-	"github.com/notenoughtea/law_scraper/internal/config"
 	"github.com/notenoughtea/law_scraper/internal/logger"
 	"github.com/notenoughtea/law_scraper/internal/repository"
 )
 
-func SendNotificationsFromFile() error {
+// SendNotificationsFromFile повторно отправляет совпадения во вложениях из последнего сканирования,
+// записанного в журнал совпадений (matched/matches.jsonl)
+func SendNotificationsFromFile(ctx context.Context) error {
 	logger.Log.Info("════════════════════════════════════════")
 	logger.Log.Info("  НАЧАЛО ПРОЦЕССА ОТПРАВКИ УВЕДОМЛЕНИЙ")
 	logger.Log.Info("════════════════════════════════════════")
 
-	filePath := filepath.Join(config.GetMatchedDir(), "file_urls.json")
-	logger.Log.Infof("Путь к файлу с URL: %s", filePath)
-
-	data, err := os.ReadFile(filePath)
+	scanID, err := repository.LastScanID(repository.MatchQuery{FilesOnly: true})
 	if err != nil {
-		if os.IsNotExist(err) {
-			logger.Log.Warn("⚠️  Файл file_urls.json не найден, нечего отправлять")
-			return nil
-		}
-		logger.Log.Errorf("❌ Ошибка чтения файла %s: %v", filePath, err)
-		return fmt.Errorf("ошибка чтения файла: %w", err)
+		logger.Log.Errorf("❌ Ошибка чтения журнала совпадений: %v", err)
+		return fmt.Errorf("ошибка чтения журнала совпадений: %w", err)
+	}
+	if scanID == "" {
+		logger.Log.Warn("⚠️  В журнале совпадений нет файлов, нечего отправлять")
+		return nil
 	}
+	logger.Log.Infof("Сканирование из журнала совпадений: %s", scanID)
 
-	var files []repository.FileURLWithKeywords
-	if err := json.Unmarshal(data, &files); err != nil {
-		logger.Log.Errorf("❌ Ошибка парсинга JSON: %v", err)
-		return fmt.Errorf("ошибка парсинга JSON: %w", err)
+	files, err := repository.LoadMatches(repository.MatchQuery{ScanID: scanID, FilesOnly: true})
+	if err != nil {
+		logger.Log.Errorf("❌ Ошибка чтения журнала совпадений: %v", err)
+		return fmt.Errorf("ошибка чтения журнала совпадений: %w", err)
 	}
 
 	logger.Log.Infof("✓ Загружено %d файлов для отправки", len(files))
 
+	notifier := LoadNotifier()
+
 	count := 0
 
 	for i, file := range files {
 		logger.Log.Infof("────────────────────────────────────────")
 		logger.Log.Infof("Обработка файла %d/%d", i+1, len(files))
-		logger.Log.Infof("  → URL: %s", file.URL)
+		logger.Log.Infof("  → URL: %s", file.FileURL)
 		logger.Log.Infof("  → Ключевые слова: %v", file.Keywords)
 		logger.Log.Infof("  → Дата публикации: %s", file.PubDate)
 		logger.Log.Infof("  → Заголовок: %s", file.Title)
@@ -53,15 +53,8 @@ func SendNotificationsFromFile() error {
 
 		// Отправляем уведомление
 		logger.Log.Infof("  → Попытка отправки уведомления %d...", count+1)
-		if err := clients.SendFileURLWithKeywords(
-			file.ProjectURL,
-			file.URL,
-			file.Keywords,
-			file.PubDate,
-			file.Title,
-			file.Description,
-		); err != nil {
-			logger.Log.Errorf("❌ Ошибка отправки уведомления для %s: %v", file.URL, err)
+		if err := notifier.Notify(ctx, file.Notification); err != nil {
+			logger.Log.Errorf("❌ Ошибка отправки уведомления для %s: %v", file.FileURL, err)
 			continue
 		}
 
@@ -73,32 +66,67 @@ func SendNotificationsFromFile() error {
 		time.Sleep(1 * time.Second)
 	}
 
+	if err := clients.FlushNotifier(ctx, notifier); err != nil {
+		logger.Log.Errorf("❌ Ошибка отправки дайджестов: %v", err)
+	}
+
 	logger.Log.Info("════════════════════════════════════════")
-	logger.Log.Infof("  ИТОГО: Отправлено %d уведомлений в Telegram из %d файлов", count, len(files))
+	logger.Log.Infof("  ИТОГО: Отправлено %d уведомлений из %d файлов", count, len(files))
 	logger.Log.Info("════════════════════════════════════════")
 	return nil
 }
 
-// truncateString обрезает строку до указанной длины
+// LoadNotifier собирает каналы доставки из data/notifiers.json.
+// Если файл не найден или ни один канал не настроен, используется Telegram из .env
+func LoadNotifier() clients.Notifier {
+	// Шаблоны перечитываются при каждом запуске, чтобы изменения применялись без пересборки
+	clients.ReloadTemplates()
+
+	configs, err := repository.LoadNotifierConfigs()
+	if err != nil {
+		logger.Log.Warnf("Не удалось загрузить каналы доставки: %v, используем Telegram из .env", err)
+	}
+
+	var notifiers []clients.Notifier
+	for _, cfg := range configs {
+		if !cfg.IsEnabled() {
+			continue
+		}
+		n, err := clients.NewNotifier(cfg)
+		if err != nil {
+			logger.Log.Warnf("Канал доставки пропущен: %v", err)
+			continue
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	if len(notifiers) == 0 {
+		notifiers = append(notifiers, clients.NewTelegramNotifier("telegram", nil))
+	}
+
+	multi := clients.NewMultiNotifier(notifiers...)
+	logger.Log.Infof("Каналы доставки уведомлений (%d): %s", multi.Len(), multi.Name())
+	return multi
+}
+
+// truncateString обрезает строку до указанного количества символов, не разрывая символы UTF-8
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	if utf8.RuneCountInString(s) <= maxLen {
 		return s
 	}
-	return s[:maxLen] + "..."
+	return string([]rune(s)[:maxLen]) + "..."
 }
 
 // RunManualScan выполняет сканирование вручную и возвращает результат
 // Использует параллельную обработку с отправкой уведомлений сразу
-func RunManualScan() (int, error) {
+func RunManualScan(ctx context.Context) (int, error) {
 	logger.Log.Info("🚀 Запуск ручного сканирования (параллельный режим)...")
 
-	const rssURL = "https://regulation.gov.ru/api/public/Rss/"
-
 	// Используем параллельную версию с отправкой уведомлений сразу
-	matchesCount, err := ScanRSSAndProjectsParallel(rssURL)
+	matchesCount, err := RunScan(ctx)
 	if err != nil {
 		logger.Log.Errorf("Ошибка сканирования RSS/проектов: %v", err)
-		return 0, err
+		return matchesCount, err
 	}
 
 	logger.Log.Infof("✅ Сканирование завершено. Найдено совпадений: %d. Уведомления отправлены сразу.", matchesCount)
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/notenoughtea/law_scraper/internal/clients"
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/matcher"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+)
+
+// Конвейер сканирования:
+//
+//	RSS -> карточка проекта -> ID файлов из стадий -> загрузка -> извлечение текста и поиск -> совпадения -> уведомление
+//
+// Каждая стадия - отдельный пул обработчиков, стадии связаны каналами с буфером
+// по числу обработчиков следующей стадии, поэтому медленный ответ API не останавливает загрузку файлов.
+// Скачанные файлы занимают память из общего бюджета (SCAN_MEMORY_MB), а текст извлекается
+// и проверяется потоком, не собираясь целиком
+
+// projectJob - элемент RSS со сведениями о проекте; files заполнены, если стадии
+// уже получены вместе с карточкой
+type projectJob struct {
+	item       dto.RSSItem
+	project    dto.Notification
+	files      []dto.StageFile
+	filesKnown bool
+}
+
+// downloadedFile - скачанный файл
+type downloadedFile struct {
+	task   fileTask
+	body   *fileBody
+	header http.Header
+}
+
+// pendingNotification - совпадение, ожидающее отправки; done вызывается после отправки,
+// чтобы отметить шаг в контрольной точке
+type pendingNotification struct {
+	n    dto.Notification
+	done func()
+}
+
+// extractedFile - файл, в котором найдены ключевые слова, с фрагментами текста вокруг них
+type extractedFile struct {
+	downloadedFile
+	found                 []string
+	snippets              []string
+	score                 int
+	fileName, contentType string
+}
+
+// pipelineWorkers - размеры пулов стадий
+type pipelineWorkers struct {
+	page, stages, download, extract, match int
+}
+
+// getPipelineWorkers читает размеры пулов стадий из настроек. Загрузку ограничивает MAX_WORKERS,
+// извлечение текста и поиск по умолчанию выполняются в один поток, чтобы не нагружать единственное ядро
+func getPipelineWorkers() pipelineWorkers {
+	return pipelineWorkers{
+		page:     config.GetStageWorkers("page", 2),
+		stages:   config.GetStageWorkers("stages", 2),
+		download: config.GetStageWorkers("download", maxWorkers),
+		extract:  config.GetStageWorkers("extract", 1),
+		match:    config.GetStageWorkers("match", 1),
+	}
+}
+
+// runStage запускает workers обработчиков, читающих in, и возвращает канал, который закрывается,
+// когда все обработчики завершились (после закрытия in)
+func runStage[In any](workers int, in <-chan In, handle func(workerID int, v In)) <-chan struct{} {
+	var wg sync.WaitGroup
+	for i := 1; i <= workers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for v := range in {
+				handle(id, v)
+			}
+		}(i)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// closeAfter закрывает канал, когда завершатся все перечисленные стадии
+func closeAfter[T any](ch chan T, stages ...<-chan struct{}) {
+	go func() {
+		for _, done := range stages {
+			<-done
+		}
+		close(ch)
+	}()
+}
+
+// scanProgress - учет выполненных шагов конвейера. Для обычного сканирования это контрольная
+// точка (scanTracker), для ретроспективного - пустой учет (см. Backfill)
+type scanProgress interface {
+	state(link string) repository.ItemCheckpoint
+	pageDone(link string)
+	filesFound(link string, files []dto.StageFile)
+	fileDone(link, fileURL string)
+}
+
+// runScanPipeline обрабатывает элементы RSS конвейером, передает каждое совпадение в deliver
+// и возвращает число совпадений. Выполненные шаги отмечаются в tracker: уже проверенные страницы
+// и файлы продолженного сканирования пропускаются. После отмены ctx стадии страниц, стадий
+// и загрузки перестают брать новую работу, а уже скачанные файлы проходят конвейер до конца
+func runScanPipeline(ctx context.Context, items []dto.RSSItem, m *matcher.Matcher, tracker scanProgress, deliver func(n dto.Notification)) int {
+	workers := getPipelineWorkers()
+	logger.Log.Infof("⚙️ Конвейер: страницы %d, стадии %d, загрузка %d, извлечение и поиск %d, совпадения %d",
+		workers.page, workers.stages, workers.download, workers.extract, workers.match)
+	memory := getScanMemory()
+	pruneSearchIndex()
+
+	// Текущие файлы не прерываем при отмене сканирования - их ограничивает только таймаут запроса
+	fileCtx := context.WithoutCancel(ctx)
+
+	var matchesCount int
+	var totalFiles int64
+
+	itemsCh := make(chan dto.RSSItem, workers.page)
+	projectsCh := make(chan projectJob, workers.stages)
+	tasksCh := make(chan fileTask, workers.download)
+	downloadedCh := make(chan downloadedFile, workers.extract)
+	extractedCh := make(chan extractedFile, workers.match)
+	notifyCh := make(chan pendingNotification, 16)
+
+	// 1. Элементы RSS
+	go func() {
+		defer close(itemsCh)
+		for _, it := range items {
+			select {
+			case itemsCh <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 2. Карточка проекта из JSON API: метаданные и совпадения в ее полях
+	pagesDone := runStage(workers.page, itemsCh, func(_ int, it dto.RSSItem) {
+		if ctx.Err() != nil {
+			return
+		}
+		pageURL := it.Link
+		var projectID string
+		if sm := projIDRe.FindStringSubmatch(pageURL); len(sm) == 2 {
+			projectID = sm[1]
+		}
+		project := projectNotification(it, projectID)
+		job := projectJob{item: it}
+
+		// Без карточки ищем по заголовку и описанию из RSS
+		searchText := it.Title + "\n" + it.Description
+		if projectID != "" {
+			details, err := clients.FetchProjectDetails(ctx, projectID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logger.Log.Warnf("карточка проекта %s недоступна: %v", projectID, err)
+			} else {
+				applyProjectCard(&project, details.Project)
+				searchText = details.SearchText()
+				job.files, job.filesKnown = dto.StageFiles(details.Stages), details.StagesKnown
+			}
+		}
+		job.project = project
+
+		if !tracker.state(it.Link).PageDone {
+			hits := m.FindAll([]byte(strings.ToLower(searchText)))
+			if found := m.Found(hits); len(found) > 0 {
+				logger.Log.Infof("✅ Найдено совпадение в карточке проекта %s: %v", pageURL, found)
+				n := project
+				n.FileURL = pageURL
+				n.Keywords = found
+				n.Score = len(hits)
+				notifyCh <- pendingNotification{n: n, done: func() { tracker.pageDone(it.Link) }}
+			} else {
+				tracker.pageDone(it.Link)
+			}
+		}
+
+		if projectID == "" {
+			tracker.filesFound(it.Link, nil)
+			return
+		}
+		projectsCh <- job
+	})
+	closeAfter(projectsCh, pagesDone)
+
+	// 3. Файлы из стадий проекта
+	stagesDone := runStage(workers.stages, projectsCh, func(_ int, job projectJob) {
+		if ctx.Err() != nil {
+			return
+		}
+		state := tracker.state(job.item.Link)
+		files := state.StageFiles()
+		if !state.FilesKnown {
+			files = job.files
+			if !job.filesKnown {
+				stages, err := clients.FetchProjectStages(ctx, job.project.ProjectID)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					logger.Log.Warnf("ошибка получения стадий проекта %s: %v", job.project.ProjectID, err)
+					tracker.filesFound(job.item.Link, nil)
+					return
+				}
+				files = dto.StageFiles(stages)
+			}
+			tracker.filesFound(job.item.Link, files)
+		}
+
+		for _, file := range files {
+			if state.FilesDone[file.URL] {
+				continue
+			}
+			select {
+			case tasksCh <- fileTask{fileURL: file.URL, file: file, project: job.project}:
+				atomic.AddInt64(&totalFiles, 1)
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	closeAfter(tasksCh, stagesDone)
+
+	// 4. Загрузка файлов
+	downloadsDone := runStage(workers.download, tasksCh, func(workerID int, task fileTask) {
+		if ctx.Err() != nil {
+			// Сканирование отменено - оставшиеся задачи пропускаем
+			return
+		}
+		logger.Log.Infof("👷 Воркер %d загружает файл: %s", workerID, task.fileURL)
+		body, header, err := downloadFile(ctx, fileCtx, memory, task.fileURL)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Отменено, пока ждали памяти - файл останется в контрольной точке
+				return
+			}
+			logger.Log.Warnf("ошибка загрузки вложения %s: %v", task.fileURL, err)
+			tracker.fileDone(task.project.ProjectURL, task.fileURL)
+			return
+		}
+		downloadedCh <- downloadedFile{task: task, body: body, header: header}
+	})
+	closeAfter(downloadedCh, downloadsDone)
+
+	// 5. Извлечение текста и поиск ключевых слов одним проходом
+	extractDone := runStage(workers.extract, downloadedCh, func(_ int, f downloadedFile) {
+		fileName, contentType := clients.StageFileName(f.task.project.ProjectID, f.task.file, f.header.Get("Content-Disposition"), f.body.sniffBytes())
+		ts := newTextScanner(m)
+		// Текст заодно сохраняется в хранилище и в поисковый индекс, если файл действительно текстовый
+		var archive *textArchive
+		if isTextDocument(f.body) {
+			archive = newTextArchive(f.task, fileName)
+		}
+		var w io.Writer = ts
+		if archive != nil {
+			w = io.MultiWriter(ts, archive.writer())
+		}
+		err := streamText(f.body, w)
+		if err != nil {
+			// Проверяем то, что успели прочитать
+			logger.Log.Warnf("ошибка извлечения текста из %s: %v", f.task.fileURL, err)
+		}
+		archive.finish(err)
+		found, snippets := ts.result()
+		if len(found) == 0 {
+			logger.Log.Debugf("совпадений не найдено в файле %s", f.task.fileURL)
+			f.body.release()
+			tracker.fileDone(f.task.project.ProjectURL, f.task.fileURL)
+			return
+		}
+		extractedCh <- extractedFile{downloadedFile: f, found: found, snippets: snippets, score: ts.hits, fileName: fileName, contentType: contentType}
+	})
+	closeAfter(extractedCh, extractDone)
+
+	// 6. Совпадения: кэш вложений, уведомление
+	matchDone := runStage(workers.match, extractedCh, func(_ int, f extractedFile) {
+		item, fileURL := f.task.project.ProjectURL, f.task.fileURL
+		logger.Log.Infof("✅ Найдено совпадение в файле %s: %v", f.task.fileURL, f.found)
+
+		n := f.task.project
+		n.FileURL = f.task.fileURL
+		n.Keywords = f.found
+		n.Snippets = f.snippets
+		n.Score = f.score
+		n.FileName = f.fileName
+		n.Stage = f.task.file.Stage
+		// Файл уже скачан - сохраняем его, чтобы канал доставки не скачивал его повторно
+		clients.CacheAttachment(f.task.fileURL, f.body.reader(), n.FileName, f.contentType)
+		f.body.release()
+		notifyCh <- pendingNotification{n: n, done: func() { tracker.fileDone(item, fileURL) }}
+	})
+	closeAfter(notifyCh, pagesDone, matchDone)
+
+	// 7. Уведомления отправляются по одному, в порядке обнаружения
+	for p := range notifyCh {
+		deliver(p.n)
+		matchesCount++
+		p.done()
+	}
+	logger.Log.Infof("📋 Всего файлов передано в обработку: %d", atomic.LoadInt64(&totalFiles))
+
+	return matchesCount
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+)
+
+const rssURL = "https://regulation.gov.ru/api/public/Rss/"
+
+// ErrScanInProgress возвращается, если сканирование уже выполняется
+var ErrScanInProgress = errors.New("сканирование уже выполняется")
+
+var (
+	scanMutex  sync.Mutex
+	scanCancel context.CancelFunc
+	scanWG     sync.WaitGroup
+)
+
+// RunScan запускает параллельное сканирование с таймаутом SCAN_TIMEOUT_MINUTES.
+// Одновременно выполняется только одно сканирование; его можно остановить через CancelScan.
+// При отмене возвращается число совпадений, найденных до остановки, и ошибка контекста
+func RunScan(ctx context.Context) (int, error) {
+	scanMutex.Lock()
+	if scanCancel != nil {
+		scanMutex.Unlock()
+		return 0, ErrScanInProgress
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	if timeout := config.GetScanTimeout(); timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		defer cancelTimeout()
+	}
+	scanCancel = cancel
+	scanWG.Add(1)
+	scanMutex.Unlock()
+
+	defer func() {
+		scanMutex.Lock()
+		scanCancel = nil
+		scanMutex.Unlock()
+		cancel()
+		scanWG.Done()
+	}()
+
+	return ScanRSSAndProjectsParallel(ctx, rssURL)
+}
+
+// CancelScan останавливает текущее сканирование. Возвращает false, если сканирование не выполняется
+func CancelScan() bool {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+	if scanCancel == nil {
+		return false
+	}
+	logger.Log.Info("⏹ Остановка сканирования...")
+	scanCancel()
+	return true
+}
+
+// IsScanRunning сообщает, выполняется ли сейчас сканирование
+func IsScanRunning() bool {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+	return scanCancel != nil
+}
+
+// WaitScan ждет, пока текущее сканирование завершит обработку файлов и сохранит состояние
+func WaitScan() {
+	scanWG.Wait()
+}
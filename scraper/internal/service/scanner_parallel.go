@@ -1,16 +1,13 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
-	"os"
-	"path/filepath"
-	"strings"
+	"context"
 	"sync"
 	"time"
 
 	"github.com/notenoughtea/law_scraper/internal/clients"
 	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/dto"
 	"github.com/notenoughtea/law_scraper/internal/logger"
 	"github.com/notenoughtea/law_scraper/internal/repository"
 )
@@ -38,18 +35,19 @@ func getMaxWorkers() int {
 
 // fileTask представляет задачу на обработку одного файла
 type fileTask struct {
-	fileURL     string
-	projectURL  string
-	projectID   string
-	pubDate     string
-	title       string
-	description string
-	keywords    []string
+	fileURL string
+	// file - описание файла из стадий проекта (стадия, исходное имя)
+	file dto.StageFile
+	// project - сведения о проекте, к которому относится файл
+	project dto.Notification
 }
 
 // ScanRSSAndProjectsParallel выполняет параллельное сканирование с отправкой уведомлений сразу
-// Возвращает количество найденных совпадений
-func ScanRSSAndProjectsParallel(rssURL string) (int, error) {
+// (см. runScanPipeline). Возвращает количество найденных совпадений.
+// При отмене ctx новые файлы не берутся в работу, воркеры дорабатывают текущие файлы,
+// дайджесты отправляются, а незавершенные элементы остаются в контрольной точке
+// и продолжаются при следующем запуске (см. scanTracker)
+func ScanRSSAndProjectsParallel(ctx context.Context, rssURL string) (int, error) {
 	// Загружаем предыдущий RSS для сравнения
 	logger.Log.Info("Загрузка предыдущего RSS для сравнения...")
 	oldFeed, err := repository.LoadPreviousRSS()
@@ -58,185 +56,65 @@ func ScanRSSAndProjectsParallel(rssURL string) (int, error) {
 	}
 
 	// Получаем новый RSS
-	feed, err := clients.FetchRSS(rssURL)
+	feed, err := clients.FetchRSS(ctx, rssURL)
 	if err != nil {
 		return 0, err
 	}
 	logger.Log.Infof("RSS загружен: %d элементов", len(feed.Channel.Items))
 
-	// Получаем только новые элементы
+	// Получаем только новые элементы и добавляем к ним незавершенные элементы прошлого сканирования
 	newItems := repository.GetNewRSSItems(feed, oldFeed)
+	tracker, items := newScanTracker(feed, oldFeed, newItems)
 
-	if len(newItems) == 0 {
+	if len(items) == 0 {
 		logger.Log.Info("✓ Новых элементов в RSS не найдено, обработка не требуется")
+		tracker.finish(feed)
 		return 0, nil
 	}
 
-	logger.Log.Infof("🆕 Найдено новых элементов для обработки: %d", len(newItems))
+	logger.Log.Infof("🆕 Найдено элементов для обработки: %d", len(items))
 
-	// Сохраняем новый RSS для следующего запуска
-	if err := repository.SaveRSS(feed); err != nil {
-		return 0, err
-	}
-	logger.Log.Info("RSS сохранен для следующего сравнения")
-
-	keywords := repository.LoadKeywords()
-	for i := range keywords {
-		keywords[i] = strings.ToLower(keywords[i])
-	}
-	logger.Log.Infof("Ищем ключевые слова: %v", keywords)
-
-	// Канал для задач на обработку файлов
-	tasksChan := make(chan fileTask, 100)
+	m := compileKeywords()
+	notifier := LoadNotifier()
 
-	// WaitGroup для синхронизации воркеров
-	var wg sync.WaitGroup
+	var delivered int64
+	var deliveredMutex sync.Mutex
+	matchesCount := runScanPipeline(ctx, items, m, tracker, func(n dto.Notification) {
+		sendNotificationImmediately(ctx, notifier, tracker.scanID(), n, nil, &delivered, &deliveredMutex)
+	})
+	clients.LogHostStats()
 
-	// Счетчик найденных совпадений
-	var matchesCount int64
-	var matchesMutex sync.Mutex
-
-	// Запускаем воркеры для обработки файлов
-	for i := 0; i < maxWorkers; i++ {
-		wg.Add(1)
-		go fileWorker(i+1, tasksChan, keywords, &wg, &matchesCount, &matchesMutex)
+	if ctx.Err() != nil {
+		logger.Log.Warnf("⏹ Сканирование прервано: %v", ctx.Err())
 	}
 
-	// Собираем все задачи (файлы для обработки)
-	totalTasks := 0
-
-	// Обрабатываем каждый новый элемент RSS
-	for _, it := range newItems {
-		pageURL := it.Link
-		html, err := fetch(pageURL)
-		if err != nil {
-			logger.Log.Warnf("ошибка загрузки страницы %s: %v", pageURL, err)
-			continue
-		}
-		lowerHTML := strings.ToLower(string(html))
-
-		// Проверяем страницу на наличие ключевых слов
-		var foundPage []string
-		for _, kw := range keywords {
-			if kw == "" {
-				continue
-			}
-			if strings.Contains(lowerHTML, kw) {
-				foundPage = append(foundPage, kw)
-			}
-		}
-
-		if len(foundPage) > 0 {
-			// Найдено совпадение на странице - отправляем сразу
-			logger.Log.Infof("✅ Найдено совпадение на странице %s: %v", pageURL, foundPage)
-			sendNotificationImmediately(pageURL, pageURL, foundPage, it.PubDate, it.Title, it.Description, &matchesCount, &matchesMutex)
-		}
-
-		// Получаем ID проекта для загрузки файлов
-		var projectID string
-		if m := projIDRe.FindStringSubmatch(pageURL); len(m) == 2 {
-			projectID = m[1]
-		}
+	// Обработанные элементы уже в rss.json; незавершенные остаются в контрольной точке
+	tracker.finish(feed)
 
-		if projectID != "" {
-			stagesURL := "https://regulation.gov.ru/api/public/PublicProjects/GetProjectStages/" + projectID
-			ids, err := clients.FetchProjectStagesFileIDs(stagesURL)
-			if err != nil {
-				logger.Log.Warnf("ошибка получения стадий проекта %s: %v", projectID, err)
-				continue
-			}
-
-			// Добавляем задачи на обработку файлов
-			for _, fid := range ids {
-				fileURL := "https://regulation.gov.ru/api/public/Files/GetFile/" + fid
-				tasksChan <- fileTask{
-					fileURL:     fileURL,
-					projectURL:  pageURL,
-					projectID:   projectID,
-					pubDate:     it.PubDate,
-					title:       it.Title,
-					description: it.Description,
-				}
-				totalTasks++
-			}
-		}
+	// Отправляем накопленные дайджесты
+	if err := clients.FlushNotifier(ctx, notifier); err != nil {
+		logger.Log.Errorf("❌ Ошибка отправки дайджестов: %v", err)
 	}
 
-	// Закрываем канал после отправки всех задач
-	close(tasksChan)
-	logger.Log.Infof("📋 Всего файлов для обработки: %d", totalTasks)
-
-	// Ждем завершения всех воркеров
-	wg.Wait()
-
-	matchesMutex.Lock()
-	count := int(matchesCount)
-	matchesMutex.Unlock()
-
-	logger.Log.Infof("✅ Все файлы обработаны. Найдено совпадений: %d", count)
-
-	return count, nil
-}
-
-// fileWorker обрабатывает файлы из канала задач
-func fileWorker(workerID int, tasksChan <-chan fileTask, keywords []string, wg *sync.WaitGroup, matchesCount *int64, matchesMutex *sync.Mutex) {
-	defer wg.Done()
-
-	for task := range tasksChan {
-		logger.Log.Infof("👷 Воркер %d обрабатывает файл: %s", workerID, task.fileURL)
-
-		// Загружаем файл
-		data, err := fetch(task.fileURL)
-		if err != nil {
-			logger.Log.Warnf("ошибка загрузки вложения %s: %v", task.fileURL, err)
-			continue
-		}
-
-		// Извлекаем текст из файла
-		var textLower string
-		if txt, err := extractDocxText(data); err == nil && txt != "" {
-			textLower = txt
-		} else {
-			textLower = decodeToLowerUTF8(data)
-		}
-
-		// Ищем ключевые слова
-		lower := []byte(textLower)
-		var found []string
-		for _, kw := range keywords {
-			if kw == "" {
-				continue
-			}
-			if bytes.Contains(lower, []byte(kw)) {
-				found = append(found, kw)
-			}
-		}
-
-		// Если найдены совпадения - отправляем уведомление сразу
-		if len(found) > 0 {
-			logger.Log.Infof("✅ Воркер %d: найдено совпадение в файле %s: %v", workerID, task.fileURL, found)
-			sendNotificationImmediately(task.projectURL, task.fileURL, found, task.pubDate, task.title, task.description, matchesCount, matchesMutex)
-		} else {
-			logger.Log.Debugf("Воркер %d: совпадений не найдено в файле %s", workerID, task.fileURL)
-		}
-
-		// Небольшая задержка между файлами для снижения нагрузки
-		time.Sleep(100 * time.Millisecond)
+	if err := ctx.Err(); err != nil {
+		return matchesCount, err
 	}
+	logger.Log.Infof("✅ Все файлы обработаны. Найдено совпадений: %d", matchesCount)
 
-	logger.Log.Infof("👷 Воркер %d завершил работу", workerID)
+	return matchesCount, nil
 }
 
-// sendNotificationImmediately отправляет уведомление сразу после обработки
-func sendNotificationImmediately(projectURL, fileURL string, keywords []string, pubDate, title, description string, matchesCount *int64, matchesMutex *sync.Mutex) {
+// sendNotificationImmediately отправляет уведомление сразу после обработки во все каналы, кроме skip
+// (уже доставленных раньше), и записывает совпадение в журнал (см. repository.AppendMatch)
+func sendNotificationImmediately(ctx context.Context, notifier clients.Notifier, scanID string, n dto.Notification, skip map[string]bool, matchesCount *int64, matchesMutex *sync.Mutex) {
 	// Логируем что передается
-	logger.Log.Infof("📤 Отправка уведомления для %s", fileURL)
-	logger.Log.Infof("   Ключевые слова: %v (количество: %d)", keywords, len(keywords))
-	logger.Log.Infof("   Заголовок: %s", title)
+	logger.Log.Infof("📤 Отправка уведомления для %s", n.FileURL)
+	logger.Log.Infof("   Ключевые слова: %v (количество: %d)", n.Keywords, len(n.Keywords))
+	logger.Log.Infof("   Заголовок: %s", n.Title)
 
 	// Проверка: если keywords пустой, логируем предупреждение
-	if len(keywords) == 0 {
-		logger.Log.Warnf("⚠️  Ключевые слова пустые для файла %s! Это не должно происходить.", fileURL)
+	if len(n.Keywords) == 0 {
+		logger.Log.Warnf("⚠️  Ключевые слова пустые для файла %s! Это не должно происходить.", n.FileURL)
 	}
 
 	// Увеличиваем счетчик совпадений
@@ -245,65 +123,20 @@ func sendNotificationImmediately(projectURL, fileURL string, keywords []string,
 	count := *matchesCount
 	matchesMutex.Unlock()
 
-	// Сохраняем в файл для отслеживания (опционально)
-	if fileURL != projectURL {
-		// Только для файлов, не для страниц
-		fileData := repository.FileURLWithKeywords{
-			URL:         fileURL,
-			ProjectURL:  projectURL,
-			Keywords:    keywords,
-			PubDate:     pubDate,
-			Title:       title,
-			Description: description,
-		}
-
-		// Добавляем в файл (аппенд) - с защитой от race condition
-		appendToFileURLs(fileData)
-	}
-
-	// Отправляем уведомление сразу
-	if err := clients.SendFileURLWithKeywords(projectURL, fileURL, keywords, pubDate, title, description); err != nil {
-		logger.Log.Errorf("❌ Ошибка отправки уведомления для %s: %v", fileURL, err)
-	} else {
-		logger.Log.Infof("✅ Уведомление #%d отправлено для %s (ключевые слова: %v)", count, fileURL, keywords)
-	}
-}
-
-// appendToFileURLs добавляет файл в список (для истории)
-// Использует mutex для защиты от race condition
-var fileURLsMutex sync.Mutex
-
-func appendToFileURLs(file repository.FileURLWithKeywords) {
-	fileURLsMutex.Lock()
-	defer fileURLsMutex.Unlock()
-
-	// Загружаем существующий список
-	existing, _ := loadFileURLs()
-
-	// Добавляем новый файл
-	existing = append(existing, file)
+	rec := repository.MatchRecord{ScanID: scanID, FoundAt: time.Now(), Notification: n}
 
-	// Сохраняем обратно
-	if err := repository.SaveFileURLs(existing); err != nil {
-		logger.Log.Warnf("Не удалось сохранить список URL-ов файлов: %v", err)
-	}
-}
-
-// loadFileURLs загружает список файлов из JSON
-func loadFileURLs() ([]repository.FileURLWithKeywords, error) {
-	filePath := filepath.Join(config.GetMatchedDir(), "file_urls.json")
-	data, err := os.ReadFile(filePath)
+	// Отправляем уведомление сразу во все каналы; доставленные каналы запоминаем,
+	// чтобы при повторной отправке не дублировать в них уведомление
+	sinks, err := clients.NotifySinks(ctx, notifier, n, skip)
+	rec.NotifiedSinks = sinks
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []repository.FileURLWithKeywords{}, nil
-		}
-		return nil, err
+		logger.Log.Errorf("❌ Ошибка отправки уведомления для %s: %v", n.FileURL, err)
+	} else {
+		rec.NotifiedAt = time.Now()
+		logger.Log.Infof("✅ Уведомление #%d отправлено для %s (ключевые слова: %v)", count, n.FileURL, n.Keywords)
 	}
 
-	var files []repository.FileURLWithKeywords
-	if err := json.Unmarshal(data, &files); err != nil {
-		return nil, err
+	if err := repository.AppendMatch(rec); err != nil {
+		logger.Log.Warnf("Не удалось записать совпадение в журнал: %v", err)
 	}
-
-	return files, nil
 }
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+	"github.com/notenoughtea/law_scraper/internal/search"
+)
+
+// searchSnippetScanBytes - сколько текста документа просматривается в поисках фрагмента для выдачи
+const searchSnippetScanBytes = 4 << 20
+
+var (
+	searchIndex     *search.Index
+	searchIndexOnce sync.Once
+)
+
+// getSearchIndex открывает полнотекстовый индекс (один на процесс, изменения других процессов
+// он дочитывает сам); nil, если открыть не удалось
+func getSearchIndex() *search.Index {
+	searchIndexOnce.Do(func() {
+		ix, err := search.Open(config.GetSearchIndexDir())
+		if err != nil {
+			logger.Log.Errorf("❌ Не удалось открыть индекс поиска: %v", err)
+			return
+		}
+		searchIndex = ix
+	})
+	return searchIndex
+}
+
+// indexDocument добавляет документ в индекс
+func indexDocument(doc search.Doc, counter *search.Counter) {
+	ix := getSearchIndex()
+	if ix == nil {
+		return
+	}
+	if err := ix.Add(doc, counter); err != nil {
+		logger.Log.Warnf("Не удалось добавить %s в индекс поиска: %v", doc.FileURL, err)
+	}
+}
+
+// pruneSearchIndex удаляет из индекса документы старше TEXT_STORE_DAYS, чтобы индекс и хранилище
+// текстов содержали одно и то же. Вызывается один раз в начале сканирования
+func pruneSearchIndex() {
+	days := config.GetTextStoreDays()
+	if days == 0 {
+		return
+	}
+	ix := getSearchIndex()
+	if ix == nil {
+		return
+	}
+	removed, err := ix.Prune(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		logger.Log.Warnf("Не удалось удалить устаревшие документы из индекса поиска: %v", err)
+		return
+	}
+	if removed > 0 {
+		logger.Log.Infof("🔍 Из индекса поиска удалено устаревших документов: %d", removed)
+	}
+}
+
+// SearchFile - документ проекта в выдаче поиска
+type SearchFile struct {
+	FileURL  string  `json:"fileUrl"`
+	FileName string  `json:"fileName,omitempty"`
+	Score    float64 `json:"score"`
+	Snippet  string  `json:"snippet,omitempty"`
+}
+
+// SearchResult - проект в выдаче поиска: оценка проекта - лучшая оценка его документов
+type SearchResult struct {
+	ProjectID  string       `json:"projectId,omitempty"`
+	ProjectURL string       `json:"projectUrl"`
+	Title      string       `json:"title,omitempty"`
+	Score      float64      `json:"score"`
+	Files      []SearchFile `json:"files"`
+}
+
+// SearchDocuments ищет запрос по индексу всех проверенных документов и возвращает
+// не больше limit проектов по убыванию релевантности с фрагментами текста
+func SearchDocuments(query string, limit int) []SearchResult {
+	ix := getSearchIndex()
+	if ix == nil {
+		return nil
+	}
+	terms := search.QueryTerms(query)
+	// Документов берем с запасом: у одного проекта их может быть несколько
+	docs := ix.Search(query, limit*5)
+
+	var results []SearchResult
+	byProject := map[string]int{}
+	for _, d := range docs {
+		i, ok := byProject[d.Doc.ProjectURL]
+		if !ok {
+			if len(results) == limit {
+				continue
+			}
+			i = len(results)
+			byProject[d.Doc.ProjectURL] = i
+			results = append(results, SearchResult{
+				ProjectID:  d.Doc.ProjectID,
+				ProjectURL: d.Doc.ProjectURL,
+				Title:      d.Doc.Title,
+				Score:      d.Score,
+			})
+		}
+		results[i].Files = append(results[i].Files, SearchFile{
+			FileURL:  d.Doc.FileURL,
+			FileName: d.Doc.FileName,
+			Score:    d.Score,
+			Snippet:  storedSnippet(d.Doc.FileID, terms),
+		})
+	}
+	return results
+}
+
+// storedSnippet вырезает из сохраненного текста фрагмент вокруг первого слова запроса
+func storedSnippet(fileID string, terms []string) string {
+	r, err := repository.OpenText(fileID)
+	if err != nil {
+		return ""
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, searchSnippetScanBytes))
+	if err != nil {
+		return ""
+	}
+	text := string(data)
+	t, ok := search.FindTerm(text, terms)
+	if !ok {
+		return ""
+	}
+	return snippetAround(text, t.Start, t.End-t.Start)
+}
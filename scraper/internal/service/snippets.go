@@ -0,0 +1,100 @@
+package service
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/notenoughtea/law_scraper/internal/dto"
+)
+
+const (
+	// maxSnippets - сколько фрагментов текста передавать в уведомление
+	maxSnippets = 3
+	// snippetRadius - сколько символов брать слева и справа от найденного слова
+	snippetRadius = 80
+)
+
+// projectNotification заполняет общие для всех совпадений проекта поля уведомления
+func projectNotification(it dto.RSSItem, projectID string) dto.Notification {
+	info := it.ProjectInfo()
+	pubDate := it.PubDate
+	if !info.Created.IsZero() {
+		pubDate = info.Created.Format("02.01.2006")
+	}
+	return dto.Notification{
+		ProjectID:     projectID,
+		ProjectNumber: info.Number,
+		ProjectURL:    it.Link,
+		PubDate:       pubDate,
+		Title:         it.Title,
+		Description:   it.Description,
+		Department:    info.Developer,
+		Procedure:     info.Procedure,
+		Kind:          info.Kind,
+		Okveds:        info.Okveds,
+	}
+}
+
+// applyProjectCard дополняет уведомление сведениями из карточки проекта: орган-разработчик
+// (в RSS вместо него обычно ответственный сотрудник), процедура, ОКВЭД и сроки обсуждения
+func applyProjectCard(n *dto.Notification, p dto.Project) {
+	if p.Title != "" {
+		n.Title = p.Title
+	}
+	if d := p.DevelopedDepartment.Description; d != "" {
+		n.Department = d
+	}
+	if p.Procedure.Description != "" {
+		n.Procedure = p.Procedure.Description
+	}
+	if n.ProjectNumber == "" {
+		n.ProjectNumber = p.ProjectID
+	}
+	if len(p.Okveds) > 0 {
+		n.Okveds = n.Okveds[:0:0]
+		for _, o := range p.Okveds {
+			n.Okveds = append(n.Okveds, o.Code())
+		}
+	}
+	if !p.StartPublicDiscussion.IsZero() {
+		n.Deadlines.DiscussionStart = p.StartPublicDiscussion.Format("02.01.2006")
+	}
+	if !p.EndPublicDiscussion.IsZero() {
+		n.Deadlines.DiscussionEnd = p.EndPublicDiscussion.Format("02.01.2006")
+	}
+}
+
+// snippetAround вырезает окно текста вокруг [start, start+length) по границам символов
+func snippetAround(text string, start, length int) string {
+	// Берем с запасом по байтам (символ UTF-8 занимает до 4 байт), затем режем по символам
+	lo := max(0, start-snippetRadius*utf8.UTFMax)
+	for lo > 0 && !utf8.RuneStart(text[lo]) {
+		lo++
+	}
+	hi := min(len(text), start+length+snippetRadius*utf8.UTFMax)
+	for hi < len(text) && !utf8.RuneStart(text[hi]) {
+		hi--
+	}
+	before := []rune(text[lo:start])
+	after := []rune(text[start+length : hi])
+
+	from := len(before) - snippetRadius
+	prefix := "…"
+	if from <= 0 {
+		from = 0
+		if lo == 0 {
+			prefix = ""
+		}
+	}
+	to := snippetRadius
+	suffix := "…"
+	if to >= len(after) {
+		to = len(after)
+		if hi == len(text) {
+			suffix = ""
+		}
+	}
+
+	snippet := string(before[from:]) + text[start:start+length] + string(after[:to])
+	return prefix + strings.Join(strings.Fields(snippet), " ") + suffix
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/notenoughtea/law_scraper/internal/clients"
+	"github.com/notenoughtea/law_scraper/internal/config"
+)
+
+// fileMemoryReserve - сколько памяти воркер резервирует под файл до начала загрузки.
+// Файлы больше резерва (по Content-Length или по факту) записываются во временный файл на диске
+const fileMemoryReserve = 16 << 20
+
+// sniffHeadSize - сколько первых байт файла на диске читаем для определения типа
+const sniffHeadSize = 64 << 10
+
+// fileBody - содержимое скачанного файла: в памяти (в пределах резерва бюджета)
+// или во временном файле на диске
+type fileBody struct {
+	data []byte
+	file *os.File
+	size int64
+	// reserved - сколько байт бюджета памяти удерживает файл
+	reserved int64
+	budget   *memoryBudget
+}
+
+// readerAt возвращает доступ к содержимому с произвольного места
+func (f *fileBody) readerAt() io.ReaderAt {
+	if f.file != nil {
+		return f.file
+	}
+	return bytes.NewReader(f.data)
+}
+
+// reader возвращает содержимое для последовательного чтения с начала
+func (f *fileBody) reader() io.Reader {
+	return io.NewSectionReader(f.readerAt(), 0, f.size)
+}
+
+// head возвращает первые n байт файла (для определения типа)
+func (f *fileBody) head(n int) []byte {
+	if f.file == nil {
+		if len(f.data) > n {
+			return f.data[:n]
+		}
+		return f.data
+	}
+	buf := make([]byte, n)
+	read, _ := f.file.ReadAt(buf, 0)
+	return buf[:read]
+}
+
+// sniffBytes возвращает данные для определения типа файла: весь файл, если он в памяти,
+// иначе его начало
+func (f *fileBody) sniffBytes() []byte {
+	if f.file == nil {
+		return f.data
+	}
+	return f.head(sniffHeadSize)
+}
+
+// release освобождает память бюджета и удаляет временный файл
+func (f *fileBody) release() {
+	f.data = nil
+	f.budget.release(f.reserved)
+	f.reserved = 0
+	if f.file != nil {
+		f.file.Close()
+		os.Remove(f.file.Name())
+		f.file = nil
+	}
+}
+
+// downloadFile скачивает файл, не выходя за бюджет памяти: резерв берется до запроса,
+// после получения заголовков лишнее возвращается в бюджет, а файл, который в резерв
+// не помещается, пишется во временный файл на диске. Ожидание резерва прерывается ctx,
+// сама загрузка - только fileCtx
+func downloadFile(ctx, fileCtx context.Context, budget *memoryBudget, fileURL string) (*fileBody, http.Header, error) {
+	reserved, err := budget.acquire(ctx, fileMemoryReserve)
+	if err != nil {
+		return nil, nil, err
+	}
+	body := &fileBody{reserved: reserved, budget: budget}
+	ok := false
+	defer func() {
+		if !ok {
+			body.release()
+		}
+	}()
+
+	resp, err := clients.GetRegulation(fileCtx, fileURL, "*/*")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, errors.New(resp.Status)
+	}
+
+	if resp.ContentLength > reserved {
+		// Заведомо не помещается в резерв - сразу на диск
+		budget.release(body.reserved)
+		body.reserved = 0
+		if err := body.spool(nil, resp.Body); err != nil {
+			return nil, nil, err
+		}
+		ok = true
+		return body, resp.Header, nil
+	}
+	if resp.ContentLength >= 0 {
+		budget.release(reserved - resp.ContentLength)
+		body.reserved = resp.ContentLength
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(int(body.reserved))
+	n, err := io.CopyN(&buf, resp.Body, body.reserved+1)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	if n > body.reserved {
+		// Размер был неизвестен и превысил резерв - дописываем остаток на диск
+		budget.release(body.reserved)
+		body.reserved = 0
+		if err := body.spool(buf.Bytes(), resp.Body); err != nil {
+			return nil, nil, err
+		}
+		ok = true
+		return body, resp.Header, nil
+	}
+
+	body.data = buf.Bytes()
+	body.size = n
+	budget.release(body.reserved - n)
+	body.reserved = n
+	ok = true
+	return body, resp.Header, nil
+}
+
+// spool записывает уже прочитанное начало и остаток ответа во временный файл
+func (f *fileBody) spool(prefix []byte, rest io.Reader) error {
+	dir := config.GetScanSpoolDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "download-*")
+	if err != nil {
+		return err
+	}
+	f.file = tmp
+	if _, err := tmp.Write(prefix); err != nil {
+		return err
+	}
+	n, err := io.Copy(tmp, rest)
+	if err != nil {
+		return err
+	}
+	f.size = int64(len(prefix)) + n
+	return nil
+}
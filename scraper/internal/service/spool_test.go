@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestDownloadFileSpillsToDisk(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		// knownLength - сервер передает Content-Length
+		knownLength bool
+		onDisk      bool
+	}{
+		{"маленький файл с длиной", 4 << 10, true, false},
+		{"маленький файл без длины", 4 << 10, false, false},
+		{"длина больше резерва", fileMemoryReserve + 1, true, true},
+		{"без длины, больше резерва", fileMemoryReserve + 1, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spoolDir := t.TempDir()
+			t.Setenv("SCAN_SPOOL_DIR", spoolDir)
+
+			content := bytes.Repeat([]byte("проект постановления "), tt.size/len("проект постановления ")+1)[:tt.size]
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.knownLength {
+					w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				} else {
+					// Без Flush маленький ответ получил бы Content-Length автоматически
+					w.(http.Flusher).Flush()
+				}
+				w.Write(content)
+			}))
+			defer srv.Close()
+
+			budget := &memoryBudget{capacity: 2 * fileMemoryReserve}
+			body, _, err := downloadFile(context.Background(), context.Background(), budget, srv.URL+"/GetFile/1")
+			if err != nil {
+				t.Fatalf("downloadFile: %v", err)
+			}
+
+			if (body.file != nil) != tt.onDisk {
+				t.Errorf("файл на диске: %v, ожидалось %v", body.file != nil, tt.onDisk)
+			}
+			wantReserved := int64(len(content))
+			if tt.onDisk {
+				wantReserved = 0
+			}
+			if body.reserved != wantReserved || budget.used != wantReserved {
+				t.Errorf("резерв файла %d, занято в бюджете %d, ожидалось %d", body.reserved, budget.used, wantReserved)
+			}
+			if body.size != int64(len(content)) {
+				t.Errorf("размер %d, ожидалось %d", body.size, len(content))
+			}
+			got, err := io.ReadAll(body.reader())
+			if err != nil || !bytes.Equal(got, content) {
+				t.Errorf("содержимое не совпадает (прочитано %d байт, err = %v)", len(got), err)
+			}
+			if head := body.head(16); !bytes.Equal(head, content[:16]) {
+				t.Errorf("head = %q", head)
+			}
+
+			body.release()
+			if budget.used != 0 {
+				t.Errorf("после release занято %d", budget.used)
+			}
+			if entries, _ := os.ReadDir(spoolDir); len(entries) != 0 {
+				t.Errorf("временные файлы не удалены: %d", len(entries))
+			}
+		})
+	}
+}
+
+func TestDownloadFileReleasesBudgetOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	budget := &memoryBudget{capacity: fileMemoryReserve}
+	if _, _, err := downloadFile(context.Background(), context.Background(), budget, srv.URL+"/GetFile/404"); err == nil {
+		t.Fatal("ожидалась ошибка для 404")
+	}
+	if budget.used != 0 {
+		t.Errorf("после ошибки занято %d", budget.used)
+	}
+}
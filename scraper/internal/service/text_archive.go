@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/notenoughtea/law_scraper/internal/clients"
+	"github.com/notenoughtea/law_scraper/internal/config"
+	"github.com/notenoughtea/law_scraper/internal/logger"
+	"github.com/notenoughtea/law_scraper/internal/matcher"
+	"github.com/notenoughtea/law_scraper/internal/repository"
+	"github.com/notenoughtea/law_scraper/internal/search"
+)
+
+// textArchive - запись извлеченного текста файла в хранилище текстов и в поисковый индекс.
+// Сохраняется не больше TEXT_STORE_MAX_MB текста, остаток отбрасывается
+type textArchive struct {
+	tw      *repository.TextWriter
+	counter *search.Counter
+	doc     search.Doc
+	// limit - сколько байт текста еще можно сохранить
+	limit     int64
+	truncated bool
+}
+
+// newTextArchive начинает архивирование текста файла; nil - если хранение отключено
+// или не удалось
+func newTextArchive(task fileTask, fileName string) *textArchive {
+	doc := search.Doc{
+		FileID:     clients.FileIDFromURL(task.fileURL),
+		FileURL:    task.fileURL,
+		FileName:   fileName,
+		ProjectID:  task.project.ProjectID,
+		ProjectURL: task.project.ProjectURL,
+		Title:      task.project.Title,
+	}
+	tw, err := repository.CreateText(repository.StoredText{
+		FileID:     doc.FileID,
+		FileURL:    doc.FileURL,
+		FileName:   doc.FileName,
+		ProjectID:  doc.ProjectID,
+		ProjectURL: doc.ProjectURL,
+		Title:      doc.Title,
+	})
+	if err != nil {
+		logger.Log.Warnf("Не удалось сохранить текст %s: %v", task.fileURL, err)
+		return nil
+	}
+	if tw == nil {
+		return nil
+	}
+	return &textArchive{tw: tw, counter: search.NewCounter(), doc: doc, limit: config.GetTextStoreMaxBytes()}
+}
+
+// writer возвращает приемник текста документа
+func (a *textArchive) writer() io.Writer {
+	return a
+}
+
+// Write сохраняет текст, пока не исчерпан лимит, и всегда принимает p целиком,
+// чтобы поиск ключевых слов в том же потоке продолжался
+func (a *textArchive) Write(p []byte) (int, error) {
+	n := len(p)
+	if int64(len(p)) > a.limit {
+		// Режем по границе символа
+		cut := int(a.limit)
+		for cut > 0 && !utf8.RuneStart(p[cut]) {
+			cut--
+		}
+		p = p[:cut]
+		a.limit = 0
+		if !a.truncated {
+			a.truncated = true
+			logger.Log.Infof("Текст %s длиннее TEXT_STORE_MAX_MB, сохраняется только начало", a.doc.FileURL)
+		}
+	} else {
+		a.limit -= int64(len(p))
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+	if _, err := a.tw.Write(p); err != nil {
+		return 0, err
+	}
+	if _, err := a.counter.Write(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// finish сохраняет текст и добавляет его в индекс, если он извлечен полностью,
+// иначе отменяет запись
+func (a *textArchive) finish(extractErr error) {
+	if a == nil {
+		return
+	}
+	if extractErr != nil {
+		a.tw.Abort()
+		return
+	}
+	if err := a.tw.Commit(); err != nil {
+		logger.Log.Warnf("Не удалось сохранить извлеченный текст: %v", err)
+		return
+	}
+	indexDocument(a.doc, a.counter)
+}
+
+// RetroMatch - проект, в сохраненных документах которого найдено слово
+type RetroMatch struct {
+	ProjectURL string
+	Title      string
+	// Files - имена файлов с совпадениями
+	Files []string
+	// Snippet - фрагмент текста вокруг первого совпадения
+	Snippet string
+}
+
+// SearchStoredTexts ищет слово в текстах документов, скачанных за последние days дней,
+// и возвращает проекты с совпадениями (от новых к старым)
+func SearchStoredTexts(ctx context.Context, keyword string, days int) ([]RetroMatch, error) {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	m := matcher.New([]string{keyword})
+	if m.Empty() {
+		return nil, nil
+	}
+
+	var matches []RetroMatch
+	byProject := map[string]int{}
+	texts := repository.ListTexts(time.Now().AddDate(0, 0, -days))
+	logger.Log.Infof("🔎 Поиск '%s' по сохраненным текстам за %d дн.: документов %d", keyword, days, len(texts))
+	for _, st := range texts {
+		if err := ctx.Err(); err != nil {
+			return matches, err
+		}
+		r, err := repository.OpenText(st.FileID)
+		if err != nil {
+			logger.Log.Warnf("Не удалось открыть сохраненный текст %s: %v", st.FileID, err)
+			continue
+		}
+		ts := newTextScanner(m)
+		_, err = io.Copy(ts, r)
+		r.Close()
+		if err != nil {
+			logger.Log.Warnf("Ошибка чтения сохраненного текста %s: %v", st.FileID, err)
+		}
+		found, snippets := ts.result()
+		if len(found) == 0 {
+			continue
+		}
+
+		name := st.FileName
+		if name == "" {
+			name = st.FileID
+		}
+		if i, ok := byProject[st.ProjectURL]; ok {
+			matches[i].Files = append(matches[i].Files, name)
+			continue
+		}
+		match := RetroMatch{ProjectURL: st.ProjectURL, Title: st.Title, Files: []string{name}}
+		if len(snippets) > 0 {
+			match.Snippet = snippets[0]
+		}
+		byProject[st.ProjectURL] = len(matches)
+		matches = append(matches, match)
+	}
+	return matches, nil
+}